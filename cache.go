@@ -26,6 +26,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/asdine/storm"
 )
@@ -34,8 +35,12 @@ const cacheFolder = "MediaScore"
 const cacheNameMovie = "movie.db"
 const cacheNameTv = "tv.db"
 const cachePerm = 0700
+const defaultCacheTTL = 90 * 24 * time.Hour     // refresh complete entries every 90 days
+const defaultCacheShortTTL = 7 * 24 * time.Hour // refresh incomplete entries or recent titles weekly
 
 var userCacheDir string
+var cacheTTL = defaultCacheTTL
+var cacheShortTTL = defaultCacheShortTTL
 
 // CacheEntry holds Movie/TV media information, Id hash (which is SHA256 hash of (Title,Year) for Movie or
 // (Title,Year,Season,Episode)), basename hash etc.
@@ -47,26 +52,24 @@ type CacheEntry struct {
 	EpisodeTitle string
 	Season       string
 	EpisodeNr    string
-	ImdbRating   string
-	RtRating     string
-	McRating     string
+	AirDate      string            // TV series episode air date, ie. "2008-01-20"
+	ImdbID       string            // IMDB Id, ie. "tt0111161"
+	Confidence   string            // match confidence: exact, year ±1 or fallback
+	Ratings      map[string]string // ratings keyed by rating provider name
+	Sources      map[string]string // rating sources (ie. "OMDb" or "RottenTomatoes") keyed by rating provider name
 	IsTv         bool
+	FetchedAt    time.Time // time when ratings have been fetched; zero for legacy entries
 }
 
 // openCache creates storm/bbolt cache databases for Movie/TV media and required folders either by using
 // USER_CACHE_DIR environment variable or using system-specific UserCacheDir()
 func openCache() (*storm.DB, *storm.DB, error) {
-	if userCacheDir == "" {
-		dir, err := os.UserCacheDir()
-		if err != nil {
-			return nil, nil, err
-		}
-
-		userCacheDir = dir
+	subDir, err := getCacheDir()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	subDir := userCacheDir + string(os.PathSeparator) + cacheFolder
-	err := os.MkdirAll(subDir, cachePerm)
+	err = os.MkdirAll(subDir, cachePerm)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -115,6 +118,15 @@ func updateCache(db *storm.DB, v renderTable) error {
 	return db.Save(&v.data)
 }
 
+// deleteCache removes a single entry from Movie/TV cache (storm/bbolt database)
+func deleteCache(db *storm.DB, entry *CacheEntry) error {
+	if db == nil {
+		return fmt.Errorf("cache not successfully initialized")
+	}
+
+	return db.DeleteStruct(entry)
+}
+
 // getCacheOne returns a single cache entry matching value with fieldName contents in the cache (storm/bbolt database)
 func getCacheOne(db *storm.DB, fieldName string, value interface{}, to interface{}) error {
 	if db == nil {
@@ -126,15 +138,74 @@ func getCacheOne(db *storm.DB, fieldName string, value interface{}, to interface
 
 // cleanCache deletes all cache databases
 func cleanCache() error {
+	subDir, err := getCacheDir()
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(subDir)
+}
+
+// getCacheDir returns cache folder either by using USER_CACHE_DIR environment variable or using system-specific
+// UserCacheDir()
+func getCacheDir() (string, error) {
 	if userCacheDir == "" {
 		dir, err := os.UserCacheDir()
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		userCacheDir = dir
 	}
 
-	subDir := userCacheDir + string(os.PathSeparator) + cacheFolder
-	return os.RemoveAll(subDir)
+	return userCacheDir + string(os.PathSeparator) + cacheFolder, nil
+}
+
+// isCacheFresh checks if cache entry is still within its TTL: entries with missing ratings and titles younger than a
+// year use shorter TTL as their ratings are likely to change; zero TTL disables expiry; entries lacking ratings of
+// any enabled provider applicable to the media type are never fresh
+func isCacheFresh(entry CacheEntry) bool {
+	for _, p := range enabledProviders {
+		if !isProviderApplicable(p, entry.IsTv) {
+			continue
+		}
+		if _, ok := entry.Ratings[p.Name()]; !ok {
+			return false
+		}
+	}
+
+	ttl := cacheTTL
+	if hasMissingRatings(entry) || isRecentTitle(entry) {
+		ttl = cacheShortTTL
+	}
+	if ttl == 0 {
+		return true
+	}
+
+	return time.Since(entry.FetchedAt) < ttl
+}
+
+// hasMissingRatings returns true if any of cache entry ratings from enabled providers applicable to the media type is
+// not available
+func hasMissingRatings(entry CacheEntry) bool {
+	for _, p := range enabledProviders {
+		if !isProviderApplicable(p, entry.IsTv) {
+			continue
+		}
+		if _, ok := parseRating(entry.Ratings[p.Name()]); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isRecentTitle returns true if media has been released in the current or the previous year
+func isRecentTitle(entry CacheEntry) bool {
+	// TV series years can be ranges such as "2011–2019"
+	year := dateYear(entry.Year)
+	if year == 0 {
+		return false
+	}
+
+	return time.Now().Year()-year < 2
 }
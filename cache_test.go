@@ -0,0 +1,85 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHasMissingRatings(t *testing.T) {
+	defer func(p []RatingProvider) { enabledProviders = p }(enabledProviders)
+	enabledProviders = []RatingProvider{&imdbProvider{}, &tvmazeProvider{}}
+
+	tests := []struct {
+		name    string
+		entry   CacheEntry
+		missing bool
+	}{
+		{"movie without TVmaze rating", CacheEntry{Ratings: map[string]string{providerImdb: "9.3",
+			providerTvmaze: "N/A"}}, false},
+		{"movie with N/A rating", CacheEntry{Ratings: map[string]string{providerImdb: "N/A"}}, true},
+		{"episode with N/A TVmaze rating", CacheEntry{IsTv: true, Ratings: map[string]string{providerImdb: "8.1",
+			providerTvmaze: "N/A"}}, true},
+		{"episode with all ratings", CacheEntry{IsTv: true, Ratings: map[string]string{providerImdb: "8.1",
+			providerTvmaze: "7.9"}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := hasMissingRatings(tt.entry); got != tt.missing {
+			t.Errorf("%v: hasMissingRatings() = %v, want %v", tt.name, got, tt.missing)
+		}
+	}
+}
+
+func TestIsCacheFreshTvOnly(t *testing.T) {
+	defer func(p []RatingProvider) { enabledProviders = p }(enabledProviders)
+	enabledProviders = []RatingProvider{&imdbProvider{}, &tvmazeProvider{}}
+
+	movie := CacheEntry{Year: "1994", Ratings: map[string]string{providerImdb: "9.3"}, FetchedAt: time.Now()}
+	if !isCacheFresh(movie) {
+		t.Errorf("movie without TVmaze rating is not fresh")
+	}
+
+	episode := CacheEntry{Year: "1994", IsTv: true, Ratings: map[string]string{providerImdb: "8.1"},
+		FetchedAt: time.Now()}
+	if isCacheFresh(episode) {
+		t.Errorf("episode without TVmaze rating is fresh")
+	}
+}
+
+func TestEnableCacheProviders(t *testing.T) {
+	defer func(p []RatingProvider, key string) { enabledProviders, omdbKey = p, key }(enabledProviders, omdbKey)
+	omdbKey = ""
+
+	if err := enableCacheProviders(nil); err != nil || len(enabledProviders) != len(ratingProviders) {
+		t.Errorf("enableCacheProviders(nil) enabled %d providers, want %d: %v", len(enabledProviders),
+			len(ratingProviders), err)
+	}
+	if err := enableCacheProviders([]string{"rt", "imdb", "rt"}); err != nil || len(enabledProviders) != 2 ||
+		enabledProviders[0].Name() != providerRt || enabledProviders[1].Name() != providerImdb {
+		t.Errorf("enableCacheProviders(rt,imdb,rt) enabled %v: %v", enabledProviders, err)
+	}
+	if err := enableCacheProviders([]string{"bogus"}); err == nil {
+		t.Errorf("enableCacheProviders(bogus) succeeded")
+	}
+}
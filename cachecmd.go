@@ -0,0 +1,399 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pborman/getopt"
+	log "github.com/sirupsen/logrus"
+)
+
+const cacheCommand = "cache" // cache maintenance subcommand
+
+const cacheTypeMovie = "movie"
+const cacheTypeTv = "tv"
+
+var cacheTypes = []string{cacheTypeMovie, cacheTypeTv}
+
+// cacheFilter selects cache entries by title, year, season, episode, media type, age and ratings state
+type cacheFilter struct {
+	title     string
+	year      string
+	season    string
+	episode   string
+	mediaType string
+	olderThan time.Duration
+	missing   bool
+	stale     bool
+}
+
+// cacheItem is a cache entry together with a database it has been loaded from
+type cacheItem struct {
+	db    *storm.DB
+	entry CacheEntry
+}
+
+// runCacheCommand parses cache subcommand arguments, runs the subcommand and returns process exit code
+func runCacheCommand(args []string) int {
+	set := getopt.New()
+	set.SetProgram("mediascore cache list|show|stats|delete|prune|export|import")
+	set.SetParameters("[file]")
+
+	var filter cacheFilter
+	helpFlag := set.BoolLong("help", 'h', "display help")
+	dryRunFlag := set.BoolLong("dry-run", 'n', "only display entries that would be deleted")
+	format := exportNDJSON
+	set.EnumVarLong(&format, "format", 0, exportFormats, "export format (json, ndjson)", "format")
+	set.StringVarLong(&filter.title, "title", 't', "media title (case-insensitive)", "title")
+	set.StringVarLong(&filter.year, "year", 'y', "media year", "year")
+	set.StringVarLong(&filter.season, "season", 0, "TV series season", "season")
+	set.StringVarLong(&filter.episode, "episode", 0, "TV series episode", "episode")
+	set.EnumVarLong(&filter.mediaType, "type", 0, cacheTypes, "media type (movie, tv)", "type")
+	set.DurationVarLong(&filter.olderThan, "older-than", 0, "entries fetched earlier than this", "duration")
+	set.BoolVarLong(&filter.missing, "missing", 0, "entries with N/A ratings")
+	set.BoolVarLong(&filter.stale, "stale", 0, "entries that would be refreshed due to cache TTL")
+
+	// First argument is the subcommand name, acting as a program name for the option parser
+	if len(args) < 1 || args[0] == "-h" || args[0] == "--help" {
+		set.PrintUsage(os.Stderr)
+		return 0
+	}
+	set.Parse(args)
+	if *helpFlag {
+		set.PrintUsage(os.Stderr)
+		return 0
+	}
+
+	// Rating columns and freshness checks use all cached providers, or those given by --providers
+	if err := enableCacheProviders(*providersFlag); err != nil {
+		log.Errorf("Unable to enable rating providers: %v", err)
+		return 1
+	}
+
+	dbMovie, dbTv, err := openCache()
+	if err != nil {
+		log.Errorf("Unable to open cache: %v", err)
+		return 1
+	}
+	defer closeCache(dbTv)
+	defer closeCache(dbMovie)
+
+	switch args[0] {
+	case "list":
+		err = cacheList(dbMovie, dbTv, filter)
+	case "show":
+		err = cacheShow(dbMovie, dbTv, filter)
+	case "stats":
+		err = cacheStats(dbMovie, dbTv)
+	case "delete":
+		if filter.title == "" {
+			err = fmt.Errorf("deleting requires media title")
+			break
+		}
+		err = cacheDelete(dbMovie, dbTv, filter, *dryRunFlag)
+	case "prune":
+		if filter == (cacheFilter{}) {
+			err = fmt.Errorf("pruning requires at least one filter")
+			break
+		}
+		err = cacheDelete(dbMovie, dbTv, filter, *dryRunFlag)
+	case "export":
+		err = cacheExport(dbMovie, dbTv, filter, format, set.Arg(0))
+	case "import":
+		err = cacheImport(dbMovie, dbTv, filter, set.Arg(0))
+	default:
+		set.PrintUsage(os.Stderr)
+		return 1
+	}
+
+	if err != nil {
+		log.Errorf("Cache %v failed: %v", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+// match checks if cache entry matches all filter criteria
+func (f cacheFilter) match(e CacheEntry) bool {
+	switch {
+	case f.title != "" && !strings.EqualFold(f.title, e.Title):
+		return false
+	case f.year != "" && !strings.HasPrefix(e.Year, f.year):
+		return false
+	case f.season != "" && f.season != e.Season:
+		return false
+	case f.episode != "" && f.episode != e.EpisodeNr:
+		return false
+	case f.mediaType == cacheTypeMovie && e.IsTv, f.mediaType == cacheTypeTv && !e.IsTv:
+		return false
+	case f.olderThan > 0 && time.Since(e.FetchedAt) < f.olderThan:
+		return false
+	case f.missing && !hasMissingRatings(e):
+		return false
+	case f.stale && isCacheFresh(e):
+		return false
+	}
+
+	return true
+}
+
+// loadCacheItems loads all entries from Movie and TV cache matching filter, sorted by title, year, season and
+// episode
+func loadCacheItems(dbMovie, dbTv *storm.DB, filter cacheFilter) ([]cacheItem, error) {
+	var items []cacheItem
+
+	for _, db := range []*storm.DB{dbMovie, dbTv} {
+		var entries []CacheEntry
+		if err := db.All(&entries); err != nil {
+			return nil, err
+		}
+
+		for _, v := range entries {
+			if filter.match(v) {
+				items = append(items, cacheItem{db: db, entry: v})
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return naturalLess(items[i].entry, items[j].entry)
+	})
+
+	return items, nil
+}
+
+// cacheList displays all matching cache entries with their ratings and age
+func cacheList(dbMovie, dbTv *storm.DB, filter cacheFilter) error {
+	items, err := loadCacheItems(dbMovie, dbTv, filter)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	header := append([]string{"Type", "Title", "Year", "Episode Title", "Season", "Episode Nr"}, ratingHeaders()...)
+	table.SetHeader(append(header[:len(header)-1], "Age"))
+	table.SetCaption(true, fmt.Sprintf("Cached entries: %d ----------^", len(items)))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, v := range items {
+		e := v.entry
+		row := []string{cacheEntryType(e), e.Title, e.Year, e.EpisodeTitle, e.Season, e.EpisodeNr}
+		for _, p := range enabledProviders {
+			row = append(row, getRating(e, p.Name()))
+		}
+		table.Append(append(row, formatAge(e.FetchedAt)))
+	}
+	table.Render()
+
+	return nil
+}
+
+// cacheShow displays all details of cache entries matching title and optionally year, season and episode
+func cacheShow(dbMovie, dbTv *storm.DB, filter cacheFilter) error {
+	if filter.title == "" {
+		return fmt.Errorf("showing requires media title")
+	}
+
+	items, err := loadCacheItems(dbMovie, dbTv, filter)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("media %q not found in cache", filter.title)
+	}
+
+	for i, v := range items {
+		e := v.entry
+		if i > 0 {
+			fmt.Print("\n")
+		}
+
+		fmt.Printf("%-18s %v\n", "Type:", cacheEntryType(e))
+		fmt.Printf("%-18s %v\n", "Title:", e.Title)
+		fmt.Printf("%-18s %v\n", "Year:", e.Year)
+		if e.IsTv {
+			fmt.Printf("%-18s %v\n", "Episode title:", e.EpisodeTitle)
+			fmt.Printf("%-18s %v\n", "Season:", e.Season)
+			fmt.Printf("%-18s %v\n", "Episode:", e.EpisodeNr)
+			fmt.Printf("%-18s %v\n", "Air date:", e.AirDate)
+		}
+		for _, p := range ratingProviders {
+			if v, ok := e.Ratings[p.Name()]; ok {
+				if src, ok := e.Sources[p.Name()]; ok {
+					v = fmt.Sprintf("%v (%v)", v, src)
+				}
+				fmt.Printf("%-18s %v\n", p.Header()+":", v)
+			}
+		}
+		fmt.Printf("%-18s %v\n", "IMDB Id:", e.ImdbID)
+		fmt.Printf("%-18s %v\n", "Fetched:", formatFetchedAt(e.FetchedAt))
+		fmt.Printf("%-18s %v\n", "Fresh:", isCacheFresh(e))
+		fmt.Printf("%-18s %v\n", "Id:", hex.EncodeToString(e.Id))
+	}
+
+	return nil
+}
+
+// cacheStats displays number of entries, entries with missing ratings, stale entries and disk size of Movie and TV
+// cache
+func cacheStats(dbMovie, dbTv *storm.DB) error {
+	subDir, err := getCacheDir()
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Cache", "Entries", "Missing ratings", "Stale", "Size"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	caches := []struct {
+		name, fileName string
+		db             *storm.DB
+	}{{cacheTypeMovie, cacheNameMovie, dbMovie}, {cacheTypeTv, cacheNameTv, dbTv}}
+
+	var totalEntries, totalMissing, totalStale int
+	var totalSize int64
+	for _, c := range caches {
+		var entries []CacheEntry
+		if err := c.db.All(&entries); err != nil {
+			return err
+		}
+
+		var missing, stale int
+		for _, e := range entries {
+			if hasMissingRatings(e) {
+				missing++
+			}
+			if !isCacheFresh(e) {
+				stale++
+			}
+		}
+
+		var size int64
+		if fi, err := os.Stat(subDir + string(os.PathSeparator) + c.fileName); err == nil {
+			size = fi.Size()
+		}
+
+		table.Append([]string{c.name, fmt.Sprint(len(entries)), fmt.Sprint(missing), fmt.Sprint(stale),
+			formatSize(size)})
+		totalEntries += len(entries)
+		totalMissing += missing
+		totalStale += stale
+		totalSize += size
+	}
+
+	table.Append([]string{"total", fmt.Sprint(totalEntries), fmt.Sprint(totalMissing), fmt.Sprint(totalStale),
+		formatSize(totalSize)})
+	table.Render()
+
+	return nil
+}
+
+// cacheDelete deletes all cache entries matching filter, or only displays them in dry-run mode
+func cacheDelete(dbMovie, dbTv *storm.DB, filter cacheFilter, dryRun bool) error {
+	items, err := loadCacheItems(dbMovie, dbTv, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range items {
+		e := v.entry
+		if dryRun {
+			fmt.Printf("Would delete %v\n", formatCacheEntry(e))
+			continue
+		}
+
+		if err := deleteCache(v.db, &e); err != nil {
+			return err
+		}
+		log.Debugf("Deleted %v", formatCacheEntry(e))
+	}
+
+	if !dryRun {
+		fmt.Printf("Deleted %d cache entries.\n", len(items))
+	}
+
+	return nil
+}
+
+// cacheEntryType returns media type of a cache entry
+func cacheEntryType(e CacheEntry) string {
+	if e.IsTv {
+		return cacheTypeTv
+	}
+	return cacheTypeMovie
+}
+
+// formatCacheEntry returns a short human-readable cache entry description
+func formatCacheEntry(e CacheEntry) string {
+	if e.IsTv {
+		return fmt.Sprintf("%v (%v) S%vE%v %v", e.Title, e.Year, e.Season, e.EpisodeNr, e.EpisodeTitle)
+	}
+	return fmt.Sprintf("%v (%v)", e.Title, e.Year)
+}
+
+// formatAge returns approximate age of a cache entry in days or hours
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+
+	d := time.Since(t)
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// formatFetchedAt returns cache entry fetch time together with its age
+func formatFetchedAt(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%v (%v ago)", t.Format(time.RFC1123), formatAge(t))
+}
+
+// formatSize returns human-readable file size
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,187 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/asdine/storm"
+	log "github.com/sirupsen/logrus"
+)
+
+const exportJSON = "json"     // single JSON array
+const exportNDJSON = "ndjson" // one JSON cache entry per line
+
+var exportFormats = []string{exportJSON, exportNDJSON}
+
+// cacheExport writes all cache entries matching filter as a JSON array or NDJSON into a file or stdout
+func cacheExport(dbMovie, dbTv *storm.DB, filter cacheFilter, format, fileName string) error {
+	items, err := loadCacheItems(dbMovie, dbTv, filter)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]CacheEntry, len(items))
+	for i, v := range items {
+		entries[i] = v.entry
+	}
+
+	if fileName == "" || fileName == "-" {
+		err = encodeCacheEntries(os.Stdout, entries, format)
+	} else {
+		var f *os.File
+		f, err = os.Create(fileName)
+		if err != nil {
+			return err
+		}
+		err = encodeCacheEntries(f, entries, format)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Exported %d cache entries.", len(entries))
+	return nil
+}
+
+// encodeCacheEntries writes cache entries either as an indented JSON array or as NDJSON
+func encodeCacheEntries(w io.Writer, entries []CacheEntry, format string) error {
+	enc := json.NewEncoder(w)
+	if format == exportJSON {
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	for _, v := range entries {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheImport merges cache entries from JSON array or NDJSON file (or stdin) into Movie and TV cache: an existing
+// entry is replaced only when the imported one has been fetched more recently
+func cacheImport(dbMovie, dbTv *storm.DB, filter cacheFilter, fileName string) error {
+	r := os.Stdin
+	if fileName != "" && fileName != "-" {
+		var err error
+		r, err = os.Open(fileName)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+	}
+
+	entries, err := decodeCacheEntries(r)
+	if err != nil {
+		return err
+	}
+
+	var added, updated, skipped int
+	for _, v := range entries {
+		if len(v.Id) == 0 || v.Title == "" || !filter.match(v) {
+			skipped++
+			continue
+		}
+
+		db := dbMovie
+		if v.IsTv {
+			db = dbTv
+		}
+
+		// Conflict policy: newer wins
+		var existing CacheEntry
+		err := getCacheOne(db, "Id", v.Id, &existing)
+		switch {
+		case err == storm.ErrNotFound:
+			added++
+		case err != nil:
+			return err
+		case !v.FetchedAt.After(existing.FetchedAt):
+			skipped++
+			continue
+		default:
+			updated++
+		}
+
+		entry := v
+		if err := db.Save(&entry); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Imported cache entries: %d added, %d updated, %d skipped.", added, updated, skipped)
+	return nil
+}
+
+// decodeCacheEntries reads either a JSON array of cache entries or NDJSON with a cache entry per line
+func decodeCacheEntries(r io.Reader) ([]CacheEntry, error) {
+	br := bufio.NewReader(r)
+
+	// Peek at the first non-whitespace character to detect JSON array
+	var first byte
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			first = b
+			_ = br.UnreadByte()
+			break
+		}
+	}
+
+	var entries []CacheEntry
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		if err := dec.Decode(&entries); err != nil {
+			return nil, fmt.Errorf("invalid JSON cache export: %v", err)
+		}
+		return entries, nil
+	}
+
+	for {
+		var v CacheEntry
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid NDJSON cache export: %v", err)
+		}
+		entries = append(entries, v)
+	}
+
+	return entries, nil
+}
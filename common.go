@@ -22,9 +22,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -33,8 +35,11 @@ import (
 const defaultHTTPTimeout = 6 * time.Second // HTTP timeout at 6s
 
 type renderTable struct {
-	isCached bool
-	data     CacheEntry
+	isCached   bool
+	data       CacheEntry
+	path       string   // originating media file path
+	duplicates []string // other media file paths with the same identity
+	reason     string   // why media could not be matched; empty for matched media
 }
 
 // getMediaDoc for a given URL does a HTTP GET and returns ready goquery document
@@ -62,6 +67,22 @@ func getMediaDoc(url string, refUrl string) (*http.Response, *goquery.Document,
 	return res, doc, nil
 }
 
+// getJSON for a given URL does a HTTP GET and decodes JSON response into v
+func getJSON(url string, v interface{}) error {
+	client := &http.Client{Timeout: defaultHTTPTimeout}
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return fmt.Errorf("HTTP error %v for URL: %v", res.StatusCode, res.Request.URL.Path)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
 // zString returns integer converted to string except for zero, which results in an empty string
 func zString(input int) string {
 	if input == 0 {
@@ -85,3 +106,33 @@ func isInt(v string) bool {
 	}
 	return true
 }
+
+// parseRating converts rating string such as "7.5", "85%" or "7.5/10" into a float; second return value is false for
+// missing ("N/A") or otherwise unparseable ratings
+func parseRating(v string) (float64, bool) {
+	v = strings.TrimSpace(v)
+	if i := strings.Index(v, "/"); i >= 0 {
+		v = v[:i]
+	}
+	v = strings.TrimSuffix(v, "%")
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// dateYear returns year of a date in "YYYY-MM-DD" format (or any string starting with a year), or zero if date is
+// invalid
+func dateYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
@@ -0,0 +1,151 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const csvTypeMovie = "movie"
+const csvTypeTv = "tv"
+const csvTypeUnmatched = "unmatched"
+const csvPathHeader = "Path"
+const csvTypeHeader = "Type"
+
+// csvRenderer collects Movie and TV rows and renders them as comma or tab separated values, either into a single
+// output with a type column or into two separate files
+type csvRenderer struct {
+	w                     io.Writer
+	comma                 rune
+	splitPath             string // base path for separate Movie/TV files; empty for single output
+	movies, tv, unmatched []outputEntry
+}
+
+// newCSVRenderer initializes CSV/TSV renderer with a given field delimiter
+func newCSVRenderer(w io.Writer, comma rune, splitPath string) *csvRenderer {
+	return &csvRenderer{w: w, comma: comma, splitPath: splitPath}
+}
+
+// Append formats media entry using the same columns as console tables, plus file path
+func (c *csvRenderer) Append(v renderTable) error {
+	e := newOutputEntry(v)
+	if e.Reason != "" {
+		c.unmatched = append(c.unmatched, e)
+	} else if e.IsTv {
+		c.tv = append(c.tv, e)
+	} else {
+		c.movies = append(c.movies, e)
+	}
+
+	return nil
+}
+
+// Render writes either a single output with type column or separate Movie and TV files
+func (c *csvRenderer) Render() error {
+	if c.splitPath != "" {
+		return c.renderSplit()
+	}
+
+	w := csv.NewWriter(c.w)
+	w.Comma = c.comma
+
+	// Single output uses TV columns as movies are just lacking episode details
+	header := append(append([]string{csvTypeHeader}, tableTvHeader...), csvPathHeader)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, v := range c.movies {
+		if err := w.Write(append(append([]string{csvTypeMovie}, tvRow(v)...), pathCell(v))); err != nil {
+			return err
+		}
+	}
+	for _, v := range c.tv {
+		if err := w.Write(append(append([]string{csvTypeTv}, tvRow(v)...), pathCell(v))); err != nil {
+			return err
+		}
+	}
+	// Unmatched media has its reason in match column
+	for _, v := range c.unmatched {
+		if err := w.Write(append(append([]string{csvTypeUnmatched}, tvRow(v)...), pathCell(v))); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// renderSplit writes Movie and TV rows into separate files named after splitPath, ie. "out.csv" results in
+// "out-movies.csv" and "out-tv.csv", plus "out-unmatched.csv" if there is unmatched media
+func (c *csvRenderer) renderSplit() error {
+	ext := filepath.Ext(c.splitPath)
+	base := strings.TrimSuffix(c.splitPath, ext)
+
+	movRows := make([][]string, len(c.movies))
+	for i, v := range c.movies {
+		movRows[i] = append(movieRow(v), pathCell(v))
+	}
+	err := writeCSVFile(base+"-movies"+ext, c.comma, append([]string{}, tableMovieHeader...), movRows)
+	if err != nil {
+		return err
+	}
+
+	tvRows := make([][]string, len(c.tv))
+	for i, v := range c.tv {
+		tvRows[i] = append(tvRow(v), pathCell(v))
+	}
+	err = writeCSVFile(base+"-tv"+ext, c.comma, append([]string{}, tableTvHeader...), tvRows)
+	if err != nil || len(c.unmatched) == 0 {
+		return err
+	}
+
+	unmatchedRows := make([][]string, len(c.unmatched))
+	for i, v := range c.unmatched {
+		unmatchedRows[i] = append(unmatchedRow(v), v.Path)
+	}
+	return writeCSVFile(base+"-unmatched"+ext, c.comma, append([]string{}, tableUnmatchedHeader...), unmatchedRows)
+}
+
+// writeCSVFile creates a file and writes header with path column and all rows into it
+func writeCSVFile(fileName string, comma rune, header []string, rows [][]string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Comma = comma
+
+	if err := w.Write(append(header, csvPathHeader)); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,86 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+// dedupRenderer merges matched media entries of the same identity, ie. the same Movie found in several roots or
+// stored in several copies, into a single entry listing all of their files before passing them to the actual renderer
+type dedupRenderer struct {
+	next    mediaRenderer
+	keys    map[string]int // index of merged entry by media identity
+	entries []renderTable
+}
+
+// newDedupRenderer wraps renderer with media deduplication; entries are buffered until rendering as duplicates can
+// show up at any time
+func newDedupRenderer(next mediaRenderer) *dedupRenderer {
+	return &dedupRenderer{next: next, keys: make(map[string]int)}
+}
+
+// Append merges media entry with an already appended entry of the same identity or buffers it; unmatched media is
+// always passed through as it has no identity to compare
+func (d *dedupRenderer) Append(v renderTable) error {
+	if v.reason != "" {
+		return d.next.Append(v)
+	}
+
+	key := getMediaKey(v.data)
+	i, ok := d.keys[key]
+	if !ok {
+		d.keys[key] = len(d.entries)
+		d.entries = append(d.entries, v)
+		return nil
+	}
+
+	// The same file could be reached through overlapping roots
+	e := &d.entries[i]
+	if v.path == e.path {
+		return nil
+	}
+	for _, p := range e.duplicates {
+		if v.path == p {
+			return nil
+		}
+	}
+	e.duplicates = append(e.duplicates, v.path)
+
+	return nil
+}
+
+// Render passes merged entries in order of their first appearance to the actual renderer and renders it
+func (d *dedupRenderer) Render() error {
+	for _, v := range d.entries {
+		if err := d.next.Append(v); err != nil {
+			return err
+		}
+	}
+
+	return d.next.Render()
+}
+
+// getMediaKey returns media identity: IMDB Id if known (together with season and episode as TV series episodes
+// could be resolved to IMDB Id of the series), or cache key made of title, year, season and episode
+func getMediaKey(data CacheEntry) string {
+	if data.ImdbID != "" {
+		return data.ImdbID + "\x00" + data.Season + "\x00" + data.EpisodeNr
+	}
+	return string(data.Id)
+}
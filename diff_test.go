@@ -0,0 +1,88 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name, oldText, newText, want string
+	}{
+		{
+			name:    "no changes",
+			oldText: "a\nb\n",
+			newText: "a\nb\n",
+			want:    "",
+		},
+		{
+			name:    "changes at the start and end of a file",
+			oldText: "x\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			newText: "1\n2\n3\n4\n5\n6\n7\n8\n9\ny\n",
+			want: `--- old
++++ new
+@@ -1,4 +1,3 @@
+-x
+ 1
+ 2
+ 3
+@@ -8,3 +7,4 @@
+ 7
+ 8
+ 9
++y
+`,
+		},
+		{
+			name:    "changes close to each other share a hunk",
+			oldText: "1\n2\n3\n4\n5\n",
+			newText: "1\nx\n3\ny\n5\n",
+			want: `--- old
++++ new
+@@ -1,5 +1,5 @@
+ 1
+-2
++x
+ 3
+-4
++y
+ 5
+`,
+		},
+		{
+			name:    "new file",
+			oldText: "",
+			newText: "a\nb\n",
+			want: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := unifiedDiff("old", "new", tt.oldText, tt.newText); got != tt.want {
+			t.Errorf("%v: unifiedDiff() =\n%v\nwant\n%v", tt.name, got, tt.want)
+		}
+	}
+}
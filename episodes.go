@@ -0,0 +1,91 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const maxEpisodeSpan = 20   // maximum number of episodes in a single multi-episode file
+const minYearEpisode = 1900 // episode numbers from here on are rather years misparsed as episodes
+
+// multiEpisodeRegexp matches multi-episode tags, ie. "S01E01E02", "S01E01-E03" or "S01E01-03"
+var multiEpisodeRegexp = regexp.MustCompile(`(?i)S\d{1,3}[ ._]?E(\d{1,4})((?:[ ._]?(?:-[ ._]?E?|E)\d{1,4})+)`)
+
+// multiEpisodePartRegexp matches subsequent episodes of multi-episode tag; dash denotes episode range
+var multiEpisodePartRegexp = regexp.MustCompile(`(?i)(-)?[ ._]?E?(\d{1,4})`)
+
+// dailyRegexp matches air dates of daily shows, ie. "Show.2019.03.14.mkv" or "Show 2019-03-14.mkv"
+var dailyRegexp = regexp.MustCompile(
+	`(?:^|[ ._-])((?:19|20)\d{2})[ ._-](0[1-9]|1[0-2])[ ._-](0[1-9]|[12]\d|3[01])(?:$|[ ._-])`)
+
+// absoluteRegexp matches anime absolute episode numbers, ie. "Show - 123.mkv" or "[Group] Show - 123 [1080p].mkv"
+var absoluteRegexp = regexp.MustCompile(`^.+?[ ._]-[ ._]+(\d{1,4})(?:v\d)?(?:$|[ ._\[(])`)
+
+// isAbsoluteEpisode checks if episode number without season is an anime absolute episode number rather than a year
+// misparsed as episode, ie. "Apollo 13 - 1995.mkv"
+func isAbsoluteEpisode(baseName string, year, episode int) bool {
+	if episode <= 0 || episode >= minYearEpisode || episode == year {
+		return false
+	}
+	return year == 0 || absoluteRegexp.MatchString(baseName)
+}
+
+// getEpisodes returns all episode numbers of multi-episode file, or nil if filename holds at most a single episode
+func getEpisodes(baseName string) []int {
+	res := multiEpisodeRegexp.FindStringSubmatch(baseName)
+	if res == nil {
+		return nil
+	}
+
+	first, _ := strconv.Atoi(res[1])
+	episodes := []int{first}
+	for _, v := range multiEpisodePartRegexp.FindAllStringSubmatch(res[2], -1) {
+		last := episodes[len(episodes)-1]
+		n, _ := strconv.Atoi(v[2])
+		// Episodes have to be ascending and reasonably close, otherwise this is not a multi-episode tag after all
+		if n <= last || n-first >= maxEpisodeSpan {
+			return nil
+		}
+
+		if v[1] != "" {
+			for i := last + 1; i < n; i++ {
+				episodes = append(episodes, i)
+			}
+		}
+		episodes = append(episodes, n)
+	}
+
+	return episodes
+}
+
+// getAirDate returns air date of a daily show episode in "2006-01-02" format, or empty string if there is none
+func getAirDate(baseName string) string {
+	res := dailyRegexp.FindStringSubmatch(baseName)
+	if res == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%v-%v-%v", res[1], res[2], res[3])
+}
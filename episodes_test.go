@@ -0,0 +1,86 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetEpisodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		episodes []int
+	}{
+		{"Show.S01E01E02.720p.mkv", []int{1, 2}},
+		{"Show.S01E01-E03.mkv", []int{1, 2, 3}},
+		{"Show.S01E01-03.mkv", []int{1, 2, 3}},
+		{"Show.s02e01.e02.e03.mkv", []int{1, 2, 3}},
+		{"Show.S01E01.720p.mkv", nil},
+		{"Show.S01E05-720p.mkv", nil},
+	}
+
+	for _, tt := range tests {
+		if got := getEpisodes(tt.name); !reflect.DeepEqual(got, tt.episodes) {
+			t.Errorf("getEpisodes(%q) = %v, want %v", tt.name, got, tt.episodes)
+		}
+	}
+}
+
+func TestGetAirDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		airDate string
+	}{
+		{"The.Daily.Show.2019.03.14.Guest.720p.mkv", "2019-03-14"},
+		{"Show 2019-03-14.mkv", "2019-03-14"},
+		{"Movie.2019.1080p.mkv", ""},
+	}
+
+	for _, tt := range tests {
+		if got := getAirDate(tt.name); got != tt.airDate {
+			t.Errorf("getAirDate(%q) = %q, want %q", tt.name, got, tt.airDate)
+		}
+	}
+}
+
+func TestIsAbsoluteEpisode(t *testing.T) {
+	tests := []struct {
+		name          string
+		year, episode int
+		absolute      bool
+	}{
+		{"Show - 123.mkv", 0, 123, true},
+		{"[Group] Show - 05 [1080p].mkv", 0, 5, true},
+		{"Show (2019) - 05.mkv", 2019, 5, true},
+		{"Apollo 13 - 1995.mkv", 1995, 1995, false},
+		{"Apollo 13 - 1995.mkv", 0, 1995, false},
+		{"Movie 2 (2010) 3.mkv", 2010, 3, false},
+		{"Movie.2010.mkv", 2010, 0, false},
+	}
+
+	for _, tt := range tests {
+		if got := isAbsoluteEpisode(tt.name, tt.year, tt.episode); got != tt.absolute {
+			t.Errorf("isAbsoluteEpisode(%q, %v, %v) = %v, want %v", tt.name, tt.year, tt.episode, got, tt.absolute)
+		}
+	}
+}
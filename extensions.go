@@ -0,0 +1,324 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const sniffSize = 512           // bytes read from the beginning of a file to detect its container format
+const sniffStreamSize = 1 << 20 // bytes scanned for video stream headers in containers shared with audio files
+
+// Matroska EBML element Ids
+const ebmlHeader = 0x1a45dfa3
+const ebmlSegment = 0x18538067
+const ebmlTracks = 0x1654ae6b
+const ebmlTrackEntry = 0xae
+const ebmlTrackType = 0x83
+const ebmlCluster = 0x1f43b675
+const ebmlVideoTrack = 1 // TrackType value of video tracks
+
+var sniffVideo bool
+var addExtensions, removeExtensions []string
+
+var errEbmlSize = errors.New("unknown or invalid EBML element size")
+
+// videoSignature is a container format magic number found at a given offset; containers shared with audio files are
+// additionally checked for video streams
+type videoSignature struct {
+	offset int
+	magic  []byte
+	video  func(r io.Reader) bool
+}
+
+// Video container signatures: Matroska/WebM, AVI, ASF/WMV, FLV, MPEG program stream, RealMedia and Ogg; MP4/MOV and
+// MPEG transport stream are detected separately
+var videoSignatures = []videoSignature{
+	{0, []byte{0x1a, 0x45, 0xdf, 0xa3}, hasMatroskaVideo},
+	{8, []byte("AVI "), nil},
+	{0, []byte{0x30, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11}, hasAsfVideo},
+	{0, []byte("FLV\x01"), nil},
+	{0, []byte{0x00, 0x00, 0x01, 0xba}, nil},
+	{0, []byte(".RMF"), nil},
+	{0, []byte("OggS"), hasOggVideo},
+}
+
+// ASF Video Media stream type GUID, found in ASF header stream properties
+var asfVideoMedia = []byte{0xc0, 0xef, 0x19, 0xbc, 0x4d, 0x5b, 0xcf, 0x11, 0xa8, 0xfd, 0x00, 0x80, 0x5f, 0x5c, 0x44, 0x2b}
+
+// Ogg video codec identification headers: Theora, OGM video and Dirac
+var oggVideoHeaders = [][]byte{[]byte("\x80theora"), []byte("\x01video"), []byte("BBCD\x00")}
+
+// Audio and sidecar file extensions which are never sniffed, as audio shares containers with video (ie. Matroska
+// .mka, ASF .wma or Ogg .oga and .opus) and sidecars are never video
+var nonVideoExtensions = map[string]bool{".aac": true, ".ac3": true, ".aif": true, ".aiff": true, ".ape": true,
+	".dts": true, ".flac": true, ".m4a": true, ".m4b": true, ".m4p": true, ".mka": true, ".mp3": true, ".mp4a": true,
+	".mpa": true, ".oga": true, ".opus": true, ".ra": true, ".spx": true, ".wav": true, ".weba": true, ".wma": true,
+	".wv": true, ".ass": true, ".idx": true, ".smi": true, ".srt": true, ".ssa": true, ".sub": true, ".sup": true,
+	".vtt": true, ".bmp": true, ".gif": true, ".jpeg": true, ".jpg": true, ".png": true, ".tbn": true, ".webp": true,
+	".cue": true, ".json": true, ".log": true, ".m3u": true, ".md5": true, ".nfo": true, ".nzb": true, ".par2": true,
+	".pdf": true, ".sfv": true, ".torrent": true, ".txt": true, ".url": true, ".xml": true}
+
+// MP4 brands of audio-only files and audio books, which are not video even though they share MP4 container
+var audioBrands = map[string]bool{"M4A ": true, "M4B ": true, "M4P ": true, "F4A ": true, "F4B ": true}
+
+// setVideoExtensions adds and removes user supplied extensions to and from permitted video extensions; extensions are
+// accepted with or without leading dot and in any case
+func setVideoExtensions(add, remove []string) error {
+	for _, v := range add {
+		ext, err := normalizeExtension(v)
+		if err != nil {
+			return err
+		}
+		videoExtensions[ext] = 1
+		delete(nonVideoExtensions, ext)
+	}
+	for _, v := range remove {
+		ext, err := normalizeExtension(v)
+		if err != nil {
+			return err
+		}
+		delete(videoExtensions, ext)
+		nonVideoExtensions[ext] = true
+	}
+	return nil
+}
+
+// normalizeExtension returns lowercase extension with leading dot
+func normalizeExtension(ext string) (string, error) {
+	v := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+	if v == "" || strings.ContainsAny(v, `./\`) {
+		return "", fmt.Errorf("invalid extension: %q", ext)
+	}
+	return "." + v, nil
+}
+
+// isVideoFile checks if file extension is one of permitted video extensions regardless of its case, or optionally
+// if file content looks like a video container for extensionless files and files with unknown extensions; removed,
+// audio and sidecar extensions are never sniffed
+func isVideoFile(fullPath string) bool {
+	ext := strings.ToLower(filepath.Ext(fullPath))
+	if _, ok := videoExtensions[ext]; ok {
+		return true
+	}
+	return sniffVideo && !nonVideoExtensions[ext] && isVideoContent(fullPath)
+}
+
+// isVideoContent checks file magic bytes against known video container signatures
+func isVideoContent(fullPath string) bool {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	buf = buf[:n]
+
+	for _, s := range videoSignatures {
+		if len(buf) >= s.offset+len(s.magic) && bytes.Equal(buf[s.offset:s.offset+len(s.magic)], s.magic) {
+			if s.video == nil {
+				return true
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return false
+			}
+			return s.video(io.LimitReader(f, sniffStreamSize))
+		}
+	}
+
+	// MP4/MOV: "ftyp" box with a major brand that is not audio-only, or QuickTime movie boxes
+	if len(buf) >= 12 {
+		switch string(buf[4:8]) {
+		case "ftyp":
+			return !audioBrands[string(buf[8:12])]
+		case "moov", "mdat", "wide", "free":
+			return true
+		}
+	}
+
+	// MPEG transport stream: sync byte repeated every 188 bytes (or 192 bytes for M2TS)
+	for _, size := range []int{188, 192} {
+		offset := size - 188
+		if len(buf) >= offset+2*size && buf[offset] == 0x47 && buf[offset+size] == 0x47 &&
+			buf[offset+2*size] == 0x47 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasAsfVideo checks if ASF header has a video stream, as ASF is shared with WMA audio
+func hasAsfVideo(r io.Reader) bool {
+	return containsAny(r, [][]byte{asfVideoMedia})
+}
+
+// hasOggVideo checks if Ogg stream headers include a video codec, as Ogg is shared with Vorbis, Opus and FLAC audio
+func hasOggVideo(r io.Reader) bool {
+	return containsAny(r, oggVideoHeaders)
+}
+
+// containsAny checks if reader content contains any of given byte sequences
+func containsAny(r io.Reader, seqs [][]byte) bool {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return false
+	}
+
+	for _, v := range seqs {
+		if bytes.Contains(buf, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMatroskaVideo checks if Matroska/WebM file has a video track, as Matroska is shared with .mka audio; tracks are
+// looked up within Segment before the first Cluster
+func hasMatroskaVideo(r io.Reader) bool {
+	br := bufio.NewReader(r)
+
+	// EBML header is followed by Segment, which may have unknown size
+	size, err := ebmlFind(br, ebmlHeader)
+	if err != nil {
+		return false
+	}
+	if _, err := br.Discard(int(size)); err != nil {
+		return false
+	}
+	if id, _, err := ebmlVint(br); err != nil || id != ebmlSegment {
+		return false
+	}
+	if _, _, err := ebmlVint(br); err != nil {
+		return false
+	}
+
+	size, err = ebmlFind(br, ebmlTracks)
+	if err != nil {
+		return false
+	}
+	tracks := bufio.NewReader(io.LimitReader(br, size))
+	for {
+		size, err := ebmlFind(tracks, ebmlTrackEntry)
+		if err != nil {
+			return false
+		}
+		entry := bufio.NewReader(io.LimitReader(tracks, size))
+		if size, err := ebmlFind(entry, ebmlTrackType); err == nil {
+			value := make([]byte, size)
+			if _, err := io.ReadFull(entry, value); err == nil && ebmlUint(value) == ebmlVideoTrack {
+				return true
+			}
+		}
+		if _, err := io.Copy(ioutil.Discard, entry); err != nil {
+			return false
+		}
+	}
+}
+
+// ebmlFind skips EBML elements until element with a given Id is found and returns its size; lookup ends at Cluster,
+// as media data is not searched
+func ebmlFind(r *bufio.Reader, id uint64) (int64, error) {
+	for {
+		v, _, err := ebmlVint(r)
+		if err != nil {
+			return 0, err
+		}
+		size, err := ebmlSize(r)
+		if err != nil {
+			return 0, err
+		}
+		if v == id {
+			return size, nil
+		}
+		if v == ebmlCluster {
+			return 0, io.EOF
+		}
+		if _, err := io.CopyN(ioutil.Discard, r, size); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// ebmlSize reads EBML element size; unknown sizes are not supported
+func ebmlSize(r *bufio.Reader) (int64, error) {
+	v, n, err := ebmlVint(r)
+	if err != nil {
+		return 0, err
+	}
+
+	marker := uint64(1) << uint(7*n)
+	size := v &^ marker
+	if size == marker-1 || size > sniffStreamSize {
+		return 0, errEbmlSize
+	}
+	return int64(size), nil
+}
+
+// ebmlVint reads EBML variable length integer and returns its value including length marker (as used by element
+// Ids) and its length in bytes
+func ebmlVint(r *bufio.Reader) (uint64, int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	n := 1
+	for mask := byte(0x80); b&mask == 0; mask >>= 1 {
+		if mask == 1 {
+			return 0, 0, errEbmlSize
+		}
+		n++
+	}
+
+	v := uint64(b)
+	for i := 1; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		v = v<<8 | uint64(b)
+	}
+	return v, n, nil
+}
+
+// ebmlUint decodes EBML unsigned integer element value
+func ebmlUint(value []byte) uint64 {
+	var v uint64
+	for _, b := range value {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
@@ -0,0 +1,114 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ebmlElement encodes EBML element with up to 126 bytes of payload
+func ebmlElement(id []byte, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	return append(append(append([]byte{}, id...), 0x80|byte(len(data))), data...)
+}
+
+// matroskaFile encodes minimal Matroska file with given track types, optionally preceded by a Cluster
+func matroskaFile(cluster bool, trackTypes ...byte) []byte {
+	header := ebmlElement([]byte{0x1a, 0x45, 0xdf, 0xa3}, ebmlElement([]byte{0x42, 0x82}, []byte("matroska")))
+	info := ebmlElement([]byte{0x15, 0x49, 0xa9, 0x66}, ebmlElement([]byte{0x2a, 0xd7, 0xb1}, []byte{0x0f, 0x42, 0x40}))
+
+	var entries [][]byte
+	for i, v := range trackTypes {
+		entries = append(entries, ebmlElement([]byte{0xae}, ebmlElement([]byte{0xd7}, []byte{byte(i + 1)}),
+			ebmlElement([]byte{0x83}, []byte{v})))
+	}
+	tracks := ebmlElement([]byte{0x16, 0x54, 0xae, 0x6b}, entries...)
+
+	segment := []byte{0x18, 0x53, 0x80, 0x67, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if cluster {
+		segment = append(segment, ebmlElement([]byte{0x1f, 0x43, 0xb6, 0x75}, []byte{0xe7, 0x81, 0x00})...)
+	}
+	return bytes.Join([][]byte{header, segment, info, tracks}, nil)
+}
+
+func TestHasMatroskaVideo(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"video and audio tracks", matroskaFile(false, 2, 1), true},
+		{"audio track only", matroskaFile(false, 2), false},
+		{"no tracks", matroskaFile(false), false},
+		{"tracks after cluster", matroskaFile(true, 1), false},
+		{"truncated", matroskaFile(false, 1)[:40], false},
+	}
+
+	for _, tt := range tests {
+		if got := hasMatroskaVideo(bytes.NewReader(tt.data)); got != tt.want {
+			t.Errorf("%v: hasMatroskaVideo() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsVideoFileSniff(t *testing.T) {
+	defer func(v bool) { sniffVideo = v }(sniffVideo)
+	sniffVideo = true
+
+	dir, err := ioutil.TempDir("", "mediascore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	asfHeader := []byte{0x30, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11, 0xa6, 0xd9, 0x00, 0xaa, 0x00, 0x62, 0xce, 0x6c}
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"Movie", matroskaFile(false, 1), true},
+		{"Movie.1080p", matroskaFile(false, 2, 1), true},
+		{"Album", matroskaFile(false, 2), false},
+		{"Movie.mka", matroskaFile(false, 1), false},
+		{"Movie.srt", matroskaFile(false, 1), false},
+		{"Movie.wmv", []byte("not a video"), true},
+		{"Movie.bin", append(append([]byte{}, asfHeader...), asfVideoMedia...), true},
+		{"Song.bin", asfHeader, false},
+		{"Video.dat", append([]byte("OggS\x00\x02"), oggVideoHeaders[0]...), true},
+		{"Song.dat", []byte("OggS\x00\x02\x01vorbis"), false},
+	}
+
+	for _, tt := range tests {
+		name := filepath.Join(dir, tt.name)
+		if err := ioutil.WriteFile(name, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := isVideoFile(name); got != tt.want {
+			t.Errorf("isVideoFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
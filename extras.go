@@ -0,0 +1,118 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const defaultMinMediaSize = 20 // skip video files smaller than 20 MB, ie. samples and broken downloads
+
+var minMediaSize = defaultMinMediaSize
+var includeGlobs, excludeGlobs []string
+
+// Plex and Kodi local extras folders, matched case-insensitively; Plex "Other" and "Shorts" folders are left out as
+// they are commonly used for regular media as well
+var extrasDirs = map[string]bool{"extras": true, "featurettes": true, "behind the scenes": true,
+	"deleted scenes": true, "interviews": true, "scenes": true, "trailers": true, "sample": true, "samples": true}
+
+// Plex local extras filename suffixes (ie. "Movie (2010)-trailer.mkv") and sample/trailer files
+var extrasFileRegexp = regexp.MustCompile(
+	`(?i)(-(behindthescenes|deleted|featurette|interview|scene|short|trailer|other|sample)|(^|[ ._-])(sample|trailer))$`)
+
+// checkGlobs validates user supplied include and exclude glob patterns
+func checkGlobs() error {
+	for _, p := range append(append([]string{}, includeGlobs...), excludeGlobs...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// matchGlobs checks if file or folder name matches any of glob patterns; patterns containing a slash are matched
+// against path relative to the walked root folder instead
+func matchGlobs(patterns []string, name, relPath string) bool {
+	for _, p := range patterns {
+		target := name
+		if strings.Contains(p, "/") {
+			target = filepath.ToSlash(relPath)
+		}
+		if ok, _ := filepath.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isExtrasDir checks if folder name is one of local extras folders
+func isExtrasDir(name string) bool {
+	return extrasDirs[strings.ToLower(name)]
+}
+
+// skipDir checks if walked folder should be skipped altogether; extras folders are still walked when include
+// patterns are given as they could match some of the files inside
+func skipDir(rootPath, osPathname string) bool {
+	if filepath.Clean(osPathname) == filepath.Clean(rootPath) {
+		return false
+	}
+
+	name := filepath.Base(osPathname)
+	relPath, _ := filepath.Rel(rootPath, osPathname)
+	if matchGlobs(excludeGlobs, name, relPath) {
+		return true
+	}
+
+	return len(includeGlobs) == 0 && isExtrasDir(name)
+}
+
+// skipFile returns the reason why walked file should be skipped, or empty string if file should be scored; user
+// exclude patterns take precedence over include patterns, which in turn override built-in extras rules and size
+// threshold
+func skipFile(rootPath, osPathname string, fi os.FileInfo) string {
+	name := filepath.Base(osPathname)
+	relPath, _ := filepath.Rel(rootPath, osPathname)
+	switch {
+	case matchGlobs(excludeGlobs, name, relPath):
+		return "excluded"
+	case matchGlobs(includeGlobs, name, relPath):
+		return ""
+	}
+
+	for _, d := range strings.Split(filepath.Dir(relPath), string(filepath.Separator)) {
+		if isExtrasDir(d) {
+			return "extras folder"
+		}
+	}
+	if extrasFileRegexp.MatchString(strings.TrimSuffix(name, filepath.Ext(name))) {
+		return "extras file"
+	}
+	if minMediaSize > 0 && fi.Size() < int64(minMediaSize)<<20 {
+		return "too small"
+	}
+
+	return ""
+}
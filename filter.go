@@ -0,0 +1,178 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+const sortTitle = "title"
+const sortYear = "year"
+const sortCombined = "combined"
+
+const orderAsc = "asc"
+const orderDesc = "desc"
+
+var sortKeys = append(providerNames(), sortTitle, sortYear, sortCombined)
+var sortOrders = []string{orderAsc, orderDesc}
+
+// filterOptions holds rating thresholds, N/A handling and sort order applied before rendering
+type filterOptions struct {
+	sortKey    string
+	order      string
+	minRatings map[string]float64 // minimum ratings keyed by rating provider name
+	excludeNA  bool
+}
+
+// filterRenderer drops media entries below rating thresholds and optionally sorts remaining entries before passing
+// them to the actual renderer
+type filterRenderer struct {
+	next    mediaRenderer
+	opts    filterOptions
+	desc    bool
+	entries []renderTable
+}
+
+// newFilterRenderer wraps renderer with rating filters and sorting; without sorting entries are passed through
+// immediately so that streaming renderers keep streaming
+func newFilterRenderer(next mediaRenderer, opts filterOptions) *filterRenderer {
+	desc := opts.order == orderDesc
+	// Natural order: best ratings first, titles and years ascending
+	if opts.order == "" {
+		desc = opts.sortKey != sortTitle && opts.sortKey != sortYear
+	}
+
+	return &filterRenderer{next: next, opts: opts, desc: desc}
+}
+
+// Append filters media entry and either buffers it for sorting or passes it through; unmatched media is always passed
+// through as it has no ratings to filter or sort by
+func (f *filterRenderer) Append(v renderTable) error {
+	if v.reason != "" {
+		return f.next.Append(v)
+	}
+	if !f.accept(v.data) {
+		return nil
+	}
+
+	if f.opts.sortKey == "" {
+		return f.next.Append(v)
+	}
+
+	f.entries = append(f.entries, v)
+	return nil
+}
+
+// Render sorts buffered entries, passes them to the actual renderer and renders it
+func (f *filterRenderer) Render() error {
+	if f.opts.sortKey != "" {
+		sort.SliceStable(f.entries, func(i, j int) bool {
+			return f.less(f.entries[i].data, f.entries[j].data)
+		})
+
+		for _, v := range f.entries {
+			if err := f.next.Append(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.next.Render()
+}
+
+// accept checks media entry against rating thresholds: N/A ratings are kept unless N/A exclusion is requested
+func (f *filterRenderer) accept(data CacheEntry) bool {
+	for name, min := range f.opts.minRatings {
+		r, ok := parseRating(data.Ratings[name])
+		if !ok {
+			if f.opts.excludeNA {
+				return false
+			}
+			continue
+		}
+		if r < min {
+			return false
+		}
+	}
+
+	if f.opts.excludeNA && f.opts.sortKey != "" {
+		if _, ok := sortValue(data, f.opts.sortKey); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// less compares two media entries by sort key, always sorting N/A values last; ties are broken by title, year,
+// season and episode
+func (f *filterRenderer) less(a, b CacheEntry) bool {
+	switch f.opts.sortKey {
+	case sortTitle:
+		ta, tb := strings.ToLower(a.Title), strings.ToLower(b.Title)
+		if ta != tb {
+			return (ta < tb) != f.desc
+		}
+	default:
+		va, okA := sortValue(a, f.opts.sortKey)
+		vb, okB := sortValue(b, f.opts.sortKey)
+		if okA != okB {
+			return okA
+		}
+		if okA && va != vb {
+			return (va < vb) != f.desc
+		}
+	}
+
+	return naturalLess(a, b)
+}
+
+// naturalLess orders media entries by title, year, season and episode
+func naturalLess(a, b CacheEntry) bool {
+	if ta, tb := strings.ToLower(a.Title), strings.ToLower(b.Title); ta != tb {
+		return ta < tb
+	}
+	if a.Year != b.Year {
+		return a.Year < b.Year
+	}
+	if sa, sb := atoiOrZero(a.Season), atoiOrZero(b.Season); sa != sb {
+		return sa < sb
+	}
+	return atoiOrZero(a.EpisodeNr) < atoiOrZero(b.EpisodeNr)
+}
+
+// sortValue returns numeric value of media entry for a given sort key; second return value is false for N/A
+func sortValue(data CacheEntry, key string) (float64, bool) {
+	switch key {
+	case sortTitle:
+		return 0, true
+	case sortYear:
+		// TV series years can be ranges such as "2011–2019"
+		y := dateYear(data.Year)
+		return float64(y), y != 0
+	case sortCombined:
+		return combinedScore(data)
+	}
+
+	return parseRating(data.Ratings[key])
+}
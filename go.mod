@@ -23,7 +23,7 @@ require (
 	github.com/sirupsen/logrus v1.4.0
 	github.com/tj/go-spin v1.1.0
 	github.com/vmihailenco/msgpack v4.0.3+incompatible // indirect
-	go.etcd.io/bbolt v1.3.2 // indirect
+	go.etcd.io/bbolt v1.3.2
 	google.golang.org/appengine v1.5.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
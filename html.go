@@ -0,0 +1,275 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// htmlRow holds table cells of a single media entry and its originating file path
+type htmlRow struct {
+	Path  string
+	Cells []string
+}
+
+// htmlSeason holds all episodes of a single TV series season
+type htmlSeason struct {
+	Season   string
+	Episodes []outputEntry
+	Rows     []htmlRow
+}
+
+// htmlSeries holds all seasons of a single TV series
+type htmlSeries struct {
+	Title   string
+	Year    string
+	Seasons []*htmlSeason
+}
+
+// htmlReport is the HTML template data
+type htmlReport struct {
+	Generated       string
+	MovieHeader     []string
+	TvHeader        []string
+	UnmatchedHeader []string
+	Movies          []htmlRow
+	Series          []*htmlSeries
+	Unmatched       []htmlRow
+}
+
+// htmlRenderer collects all media entries and renders them as a self-contained HTML report with sortable columns and
+// TV episodes grouped by series and season
+type htmlRenderer struct {
+	w                     io.Writer
+	movies, tv, unmatched []outputEntry
+}
+
+// newHTMLRenderer initializes HTML renderer
+func newHTMLRenderer(w io.Writer) *htmlRenderer {
+	return &htmlRenderer{w: w}
+}
+
+// Append pushes media entry to appropriate list
+func (h *htmlRenderer) Append(v renderTable) error {
+	if v.reason != "" {
+		h.unmatched = append(h.unmatched, newOutputEntry(v))
+	} else if v.data.IsTv {
+		h.tv = append(h.tv, newOutputEntry(v))
+	} else {
+		h.movies = append(h.movies, newOutputEntry(v))
+	}
+
+	return nil
+}
+
+// Render groups TV episodes and executes HTML template
+func (h *htmlRenderer) Render() error {
+	tmpl, err := template.New("report").Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+
+	report := htmlReport{Generated: time.Now().Format(time.RFC1123), MovieHeader: tableMovieHeader,
+		TvHeader: tableTvHeader[2:], UnmatchedHeader: unmatchedHeader(),
+		Series: groupSeries(h.tv, sortKey == "")}
+	for _, v := range h.movies {
+		report.Movies = append(report.Movies, htmlRow{Path: pathCell(v), Cells: movieRow(labelledEntry(v))})
+	}
+	for _, v := range h.unmatched {
+		report.Unmatched = append(report.Unmatched, htmlRow{Path: v.Path, Cells: append(unmatchedRow(v), v.Path)})
+	}
+
+	return tmpl.Execute(h.w, report)
+}
+
+// groupSeries groups TV episodes under their series (title and year) and season; series, seasons and episodes keep
+// the order of their first appearance (ie. as sorted by rating), unless natural order by title, season number and
+// episode number is requested
+func groupSeries(entries []outputEntry, natural bool) []*htmlSeries {
+	var series []*htmlSeries
+	seriesMap := make(map[string]*htmlSeries)
+	seasonMap := make(map[string]*htmlSeason)
+
+	for _, v := range entries {
+		seriesKey := v.Title + "\x00" + v.Year
+		s, ok := seriesMap[seriesKey]
+		if !ok {
+			s = &htmlSeries{Title: v.Title, Year: v.Year}
+			seriesMap[seriesKey] = s
+			series = append(series, s)
+		}
+
+		seasonKey := seriesKey + "\x00" + v.Season
+		season, ok := seasonMap[seasonKey]
+		if !ok {
+			season = &htmlSeason{Season: v.Season}
+			seasonMap[seasonKey] = season
+			s.Seasons = append(s.Seasons, season)
+		}
+		season.Episodes = append(season.Episodes, v)
+	}
+
+	if natural {
+		sortSeries(series)
+	}
+
+	// Series title and year are already shown in the series heading
+	for _, s := range series {
+		for _, season := range s.Seasons {
+			for _, v := range season.Episodes {
+				season.Rows = append(season.Rows, htmlRow{Path: pathCell(v), Cells: tvRow(labelledEntry(v))[2:]})
+			}
+		}
+	}
+
+	return series
+}
+
+// sortSeries sorts TV series by title and year, their seasons by season number and episodes by episode number
+func sortSeries(series []*htmlSeries) {
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Title != series[j].Title {
+			return series[i].Title < series[j].Title
+		}
+		return series[i].Year < series[j].Year
+	})
+	for _, s := range series {
+		sort.Slice(s.Seasons, func(i, j int) bool {
+			return atoiOrZero(s.Seasons[i].Season) < atoiOrZero(s.Seasons[j].Season)
+		})
+		for _, season := range s.Seasons {
+			e := season.Episodes
+			sort.Slice(e, func(i, j int) bool {
+				return atoiOrZero(e[i].Episode) < atoiOrZero(e[j].Episode)
+			})
+		}
+	}
+}
+
+// atoiOrZero converts string to integer, returning zero for invalid input
+func atoiOrZero(v string) int {
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Mediascore report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+tr:nth-child(even) td { background: #fafafa; }
+summary { font-size: 1.2em; font-weight: bold; cursor: pointer; margin: 0.5em 0; }
+h3 { margin: 0.5em 0 0.3em 1em; }
+details table { margin-left: 1em; }
+footer { color: #888; font-size: 0.8em; }
+</style>
+</head>
+<body>
+{{- if .Movies}}
+<h1>Movie Ratings</h1>
+<table class="sortable">
+<thead><tr>{{range .MovieHeader}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Movies}}
+<tr title="{{.Path}}">{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- if .Series}}
+<h1>TV Series Ratings</h1>
+{{- range .Series}}
+<details open>
+<summary>{{.Title}}{{if .Year}} ({{.Year}}){{end}}</summary>
+{{- range .Seasons}}
+<h3>Season {{.Season}}</h3>
+<table class="sortable">
+<thead><tr>{{range $.TvHeader}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr title="{{.Path}}">{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+{{- if .Unmatched}}
+<h1>Unmatched</h1>
+<table class="sortable">
+<thead><tr>{{range .UnmatchedHeader}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Unmatched}}
+<tr title="{{.Path}}">{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+<footer>Generated by mediascore on {{.Generated}}</footer>
+<script>
+(function () {
+  function value(row, idx) {
+    return row.cells[idx].textContent.trim();
+  }
+  function compare(a, b) {
+    var na = a === "" || a === "N/A", nb = b === "" || b === "N/A";
+    if (na || nb) { return na === nb ? 0 : (na ? 1 : -1); }
+    var fa = parseFloat(a), fb = parseFloat(b);
+    if (!isNaN(fa) && !isNaN(fb)) { return fa - fb; }
+    return a.localeCompare(b);
+  }
+  document.querySelectorAll("table.sortable th").forEach(function (th) {
+    th.addEventListener("click", function () {
+      var table = th.closest("table"), body = table.tBodies[0], idx = th.cellIndex;
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (r1, r2) {
+        var a = value(r1, idx), b = value(r2, idx);
+        var na = a === "" || a === "N/A", nb = b === "" || b === "N/A";
+        if (na || nb) { return compare(a, b); }
+        return asc ? compare(a, b) : compare(b, a);
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`
@@ -24,10 +24,152 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/StalkR/imdb"
+	"github.com/dkorunic/gomdb"
+	log "github.com/sirupsen/logrus"
 )
 
+const omdbSource = "OMDb" // rating source label for ratings obtained from OMDb
+
+var omdbKey string // OMDb API key: https://www.omdbapi.com/
+
+// imdbIdRegexp matches IMDB Id tags in file and folder names, ie. "{imdb-tt0111161}" or "[tt0111161]"
+var imdbIdRegexp = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(tt\d{7,})(?:[^0-9]|$)`)
+
+// imdbTagRegexp matches whole IMDB Id tags including brackets and "imdb-" prefix, for removal from file and folder names
+// before parsing
+var imdbTagRegexp = regexp.MustCompile(`(?i)[\[{(]?(?:imdb[-_ ]?)?tt\d{7,}[\]})]?`)
+
+// imdbProvider resolves media identity and IMDB ratings through OMDb, falling back to IMDB title search
+type imdbProvider struct{}
+
+// Name returns IMDB provider name
+func (p *imdbProvider) Name() string { return providerImdb }
+
+// Header returns IMDB table column header
+func (p *imdbProvider) Header() string { return "IMDB rating" }
+
+// Scale returns maximum IMDB rating
+func (p *imdbProvider) Scale() float64 { return 10 }
+
+// Configured returns true if OMDb API key is set or offline IMDB index is used instead
+func (p *imdbProvider) Configured() bool { return omdbKey != "" || offlineMode }
+
+// Offline returns true as IMDB ratings are available from offline IMDB index
+func (p *imdbProvider) Offline() bool { return true }
+
+// Resolve queries OMDb by IMDB Id if it is already known (ie. from the filename), otherwise by title and if media is
+// not found retries by IMDB Id obtained from IMDB title search; in offline mode media is resolved from offline IMDB
+// index instead
+func (p *imdbProvider) Resolve(m *mediaInfo) error {
+	if offlineMode {
+		return resolveOffline(m)
+	}
+	// OMDb has no episode lookup by air date or by absolute episode number
+	if omdbKey == "" || (m.isTv && m.season == 0) {
+		return errNoResolver
+	}
+
+	api := gomdb.Init(omdbKey)
+	query := newOmdbQuery(m)
+
+	// OMDb query by known IMDB Id (type "i") skips title search altogether
+	if m.imdbID != "" {
+		query.ImdbId = m.imdbID
+		res, err := api.MovieByImdbID(query)
+		if err == nil {
+			m.byImdbID = true
+			setOmdbResult(m, res)
+			return nil
+		}
+		log.Debugf("Could not find media with IMDB Id %v in OMDb, will retry with title: %v", m.imdbID, err)
+		query.ImdbId = ""
+	}
+
+	// OMDb query by title (type "t")
+	res, err := api.MovieByTitle(query)
+	if err != nil {
+		log.Debugf("Could not find media %q in OMDb, will retry with IMDB lookup: %v", m.title, err)
+
+		// IMDB query by title
+		imdbID, err := getImdbId(m.title, m.year)
+		if err != nil {
+			log.Debugf("Could not query IMDB with media %q: %v", m.title, err)
+			return &matchError{reason: reasonImdb, err: err}
+		}
+
+		// do another OMDb query by IMDB Id (type "i")
+		query.ImdbId = imdbID
+		res, err = api.MovieByImdbID(query)
+		if err != nil {
+			log.Debugf("Could not query IMDB with for media %q and IMDB ID %v: %v", m.title, query.ImdbId,
+				err)
+			return &matchError{reason: reasonOmdb, err: err}
+		}
+		m.fallback = true
+	}
+
+	setOmdbResult(m, res)
+	return nil
+}
+
+// Rating returns IMDB rating obtained during resolution, or queries OMDb by IMDB Id if media has been resolved by
+// another provider
+func (p *imdbProvider) Rating(m *mediaInfo) (string, error) {
+	if v, ok := m.known[providerImdb]; ok && v != "" {
+		return v, nil
+	}
+	if offlineMode && m.imdbID != "" {
+		return getOfflineRating(m.imdbID)
+	}
+	if offlineMode || omdbKey == "" || m.imdbID == "" {
+		return "N/A", nil
+	}
+
+	query := newOmdbQuery(m)
+	query.ImdbId = m.imdbID
+	res, err := gomdb.Init(omdbKey).MovieByImdbID(query)
+	if err != nil {
+		return "", err
+	}
+
+	return res.ImdbRating, nil
+}
+
+// newOmdbQuery prepares OMDb query for Movie or TV series episode
+func newOmdbQuery(m *mediaInfo) *gomdb.QueryData {
+	query := &gomdb.QueryData{Title: m.title, Year: zString(m.year)}
+	if m.isTv {
+		query.Season = zString(m.season)
+		query.Episode = zString(m.episode)
+		query.SearchType = gomdb.EpisodeSearch
+	} else {
+		query.SearchType = gomdb.MovieSearch
+	}
+
+	return query
+}
+
+// setOmdbResult stores media identity and ratings from OMDb result
+func setOmdbResult(m *mediaInfo, res *gomdb.MovieResult) {
+	m.resolvedTitle = res.Title
+	m.resolvedYear = res.Year
+	m.imdbID = res.ImdbID
+	m.tomatoURL = res.TomatoURL
+	m.known[providerImdb] = res.ImdbRating
+	// OMDb TomatoRating is an average critics rating out of 10, while TomatoMeter is the actual Tomatometer
+	for k, v := range map[string]string{providerRt: res.TomatoMeter, providerRtAudience: res.TomatoUserMeter} {
+		if v != "" && v != "N/A" {
+			m.known[k] = v
+			m.sources[k] = omdbSource
+		}
+	}
+}
+
 // getImdbId returns IMDB Id for a media title and optional media year
 func getImdbId(mediaTitle string, mediaYear int) (string, error) {
 	client := http.Client{Timeout: defaultHTTPTimeout}
@@ -52,3 +194,22 @@ func getImdbId(mediaTitle string, mediaYear int) (string, error) {
 
 	return "", fmt.Errorf("movie not found on IMDB")
 }
+
+// getImdbIdFromPath returns IMDB Id tagged in media filename or in one of its two parent folders (ie. "Movie (2010)
+// {imdb-tt1375666}/movie.mkv" or "Show [tt0903747]/Season 1/S01E01.mkv"), or empty string if there is none
+func getImdbIdFromPath(fullPath string) string {
+	name := fullPath
+	for i := 0; i < 3; i++ {
+		if res := imdbIdRegexp.FindStringSubmatch(filepath.Base(name)); res != nil {
+			return strings.ToLower(res[1])
+		}
+		name = filepath.Dir(name)
+	}
+
+	return ""
+}
+
+// stripImdbId removes IMDB Id tags from file or folder name, so that they are not mistaken for (or cut) media title
+func stripImdbId(name string) string {
+	return strings.TrimSpace(imdbTagRegexp.ReplaceAllString(name, ""))
+}
@@ -0,0 +1,44 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import "testing"
+
+func TestStripImdbId(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"{imdb-tt0111161}", ""},
+		{"tt0111161", ""},
+		{"The Shawshank Redemption (1994) {imdb-tt0111161}", "The Shawshank Redemption (1994)"},
+		{"Inception.2010.[tt1375666].1080p", "Inception.2010..1080p"},
+		{"Show [imdb tt0903747]", "Show"},
+		{"Breaking Bad (tt0903747)", "Breaking Bad"},
+	}
+
+	for _, tt := range tests {
+		if got := stripImdbId(tt.name); got != tt.want {
+			t.Errorf("stripImdbId(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonDocument is a top-level JSON output document with separate Movie, TV and unmatched media arrays
+type jsonDocument struct {
+	Movies    []outputEntry `json:"movies"`
+	Tv        []outputEntry `json:"tv"`
+	Unmatched []outputEntry `json:"unmatched"`
+}
+
+// jsonRenderer collects all media entries and renders them as a single JSON document
+type jsonRenderer struct {
+	w   io.Writer
+	doc jsonDocument
+}
+
+// newJSONRenderer initializes JSON renderer with empty Movie, TV and unmatched media arrays
+func newJSONRenderer(w io.Writer) *jsonRenderer {
+	return &jsonRenderer{w: w, doc: jsonDocument{Movies: []outputEntry{}, Tv: []outputEntry{},
+		Unmatched: []outputEntry{}}}
+}
+
+// Append pushes media entry to appropriate array
+func (j *jsonRenderer) Append(v renderTable) error {
+	if v.reason != "" {
+		j.doc.Unmatched = append(j.doc.Unmatched, newOutputEntry(v))
+	} else if v.data.IsTv {
+		j.doc.Tv = append(j.doc.Tv, newOutputEntry(v))
+	} else {
+		j.doc.Movies = append(j.doc.Movies, newOutputEntry(v))
+	}
+
+	return nil
+}
+
+// Render writes indented JSON document
+func (j *jsonRenderer) Render() error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(j.doc)
+}
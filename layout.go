@@ -0,0 +1,97 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/middelink/go-parse-torrent-name"
+)
+
+// seasonDirRegexp matches season folder names, ie. "Season 02", "Series 1", "S01" or "Staffel 3"
+var seasonDirRegexp = regexp.MustCompile(`(?i)^(?:season|series|staffel|saison|temporada|s)[ ._-]*(\d{1,3})$`)
+
+// episodeOnlyRegexp matches filenames starting with just an episode number, ie. "E03", "Episode 3" or "03 - Title"
+var episodeOnlyRegexp = regexp.MustCompile(`(?i)^(?:e|ep|episode)?[ ._-]*(\d{1,3})(?:$|[ ._-])`)
+
+// inferFromDirs fills in TV series title, year, season and episode missing from the filename by using Kodi/Plex
+// folder layout conventions: "Show (2019)/Season 02/02x05 - Title.mkv" or "Show (2019)/S01/E03.mkv"
+func inferFromDirs(fullPath, mediaTitle string, mediaYear, mediaSeason, mediaEpisode int) (string, int, int, int) {
+	parent := filepath.Dir(fullPath)
+	seriesDir := parent
+
+	dirSeason := 0
+	if res := seasonDirRegexp.FindStringSubmatch(filepath.Base(parent)); res != nil {
+		dirSeason, _ = strconv.Atoi(res[1])
+		seriesDir = filepath.Dir(parent)
+	}
+
+	// Season folder makes a bare episode number in the filename meaningful
+	if mediaEpisode == 0 && dirSeason > 0 {
+		base := strings.TrimSuffix(filepath.Base(fullPath), filepath.Ext(fullPath))
+		if res := episodeOnlyRegexp.FindStringSubmatch(base); res != nil {
+			mediaEpisode, _ = strconv.Atoi(res[1])
+			mediaTitle = ""
+		}
+	}
+
+	// Only TV series episodes are inferred from folders
+	if mediaEpisode == 0 {
+		return mediaTitle, mediaYear, mediaSeason, mediaEpisode
+	}
+	if mediaSeason == 0 {
+		mediaSeason = dirSeason
+	}
+
+	dirTitle, dirYear := getDirTitle(seriesDir)
+	switch {
+	case dirTitle == "":
+	case mediaTitle == "":
+		mediaTitle = dirTitle
+		if mediaYear == 0 {
+			mediaYear = dirYear
+		}
+	case mediaYear == 0 && normalizeTitle(mediaTitle) == normalizeTitle(dirTitle):
+		mediaYear = dirYear
+	}
+
+	return mediaTitle, mediaYear, mediaSeason, mediaEpisode
+}
+
+// getDirTitle parses TV series title and optional year from series folder name, ie. "Show Name (2019)"
+func getDirTitle(dir string) (string, int) {
+	name := filepath.Base(dir)
+	if name == "." || name == string(os.PathSeparator) || seasonDirRegexp.MatchString(name) {
+		return "", 0
+	}
+
+	info, err := parsetorrentname.Parse(stripImdbId(name))
+	if err != nil {
+		return "", 0
+	}
+
+	return strings.Trim(info.Title, "."), info.Year
+}
@@ -36,8 +36,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
-	"github.com/olekukonko/tablewriter"
-
 	"github.com/middelink/go-parse-torrent-name"
 
 	"github.com/karrick/godirwalk"
@@ -47,28 +45,64 @@ import (
 const defaultPathnameQueueSize = 128                // store up to 128 path names to score
 const defaultSpinningDelay = time.Millisecond * 200 // delay between spinner animations
 
-var helpFlag, cleanFlag *bool
+var helpFlag, cleanFlag, splitFlag, excludeNAFlag, writeNfoFlag, dryRunFlag, duplicatesFlag *bool
+var outputFileFlag, minImdbFlag, minRtFlag, minMcFlag, importImdbFlag *string
+var weightsFlag, providersFlag, minFlag *[]string
+var outputFormat = outputTable
+var sortKey, sortOrder string
 var videoExtensions map[string]int
 var tableTvHeader, tableMovieHeader []string
 var cacheMovie, cacheTv *storm.DB
 
 func init() {
+	getopt.SetParameters("[path ...] | cache list|show|stats|delete|prune|export|import [options]")
 	helpFlag = getopt.BoolLong("help", 'h', "display help")
 	cleanFlag = getopt.BoolLong("clean", 'c', "clean cache before scoring media")
-
-	// Permitted video extensions
-	videoExtensions = map[string]int{".3g2": 1, ".3gp": 1, ".3gp2": 1, ".asf": 1, ".avi": 1, ".divx": 1, ".flv": 1,
-		".mk3d": 1, ".m4v": 1, ".mk2": 1, ".mka": 1, ".mkv": 1, ".mov": 1, ".mp4": 1, ".mp4a": 1, ".mpeg": 1, ".mpg": 1,
-		".ogg": 1, ".ogm": 1, ".ogv": 1, ".qt": 1, ".ra": 1, ".ram": 1, ".rm": 1, ".ts": 1, ".wav": 1, ".webm": 1,
-		".wma": 1, ".wmv": 1, ".iso": 1, ".vob": 1}
-
-	// TV/Movie headers in rendered tables
-	tableTvHeader = []string{"Title", "Year", "Episode Title", "Season", "Episode Nr", "IMDB rating", "RT rating",
-		"Metacritic rating"}
-	tableMovieHeader = []string{"Title", "Year", "IMDB rating", "RT rating", "Metacritic rating"}
+	getopt.EnumVarLong(&outputFormat, "output", 'o', outputFormats, "output format (table, json, ndjson, csv, tsv, markdown, html)",
+		"format")
+	outputFileFlag = getopt.StringLong("output-file", 'f', "", "write output to a file instead of stdout", "file")
+	splitFlag = getopt.BoolLong("split", 's', "write Movie and TV media to separate files (csv, tsv)")
+	providersFlag = getopt.ListLong("providers", 'p', "enabled rating providers in order ("+
+		strings.Join(providerNames(), ", ")+"; default: all configured)", "list")
+	getopt.EnumVarLong(&sortKey, "sort", 0, sortKeys, "sort by rating provider name, title, year or combined", "key")
+	getopt.EnumVarLong(&sortOrder, "order", 0, sortOrders, "sort order (asc, desc)", "order")
+	minFlag = getopt.ListLong("min", 0, "minimum ratings by rating provider (ie. imdb=7.5,rt=80)", "list")
+	minImdbFlag = getopt.StringLong("min-imdb", 0, "", "minimum IMDB rating", "rating")
+	minRtFlag = getopt.StringLong("min-rt", 0, "", "minimum RottenTomatoes Tomatometer", "rating")
+	minMcFlag = getopt.StringLong("min-mc", 0, "", "minimum Metacritic Metascore", "rating")
+	excludeNAFlag = getopt.BoolLong("exclude-na", 0, "exclude media with N/A sort key or filtered ratings")
+	getopt.DurationVarLong(&cacheTTL, "cache-ttl", 0, "refresh cached ratings older than this (0 disables)",
+		"duration")
+	getopt.DurationVarLong(&cacheShortTTL, "cache-ttl-short", 0,
+		"refresh cached N/A ratings and recent titles older than this (0 disables)", "duration")
+	weightsFlag = getopt.ListLong("weights", 0, "combined score weights by rating provider (ie. imdb=2,mc=0)",
+		"list")
+	writeNfoFlag = getopt.BoolLong("write-nfo", 0, "write ratings into Kodi .nfo files next to media files")
+	dryRunFlag = getopt.BoolLong("dry-run", 'n', "only display .nfo changes as a diff instead of writing them")
+	getopt.BoolVarLong(&offlineMode, "offline", 0, "resolve media and IMDB ratings from offline IMDB index only")
+	importImdbFlag = getopt.StringLong("import-imdb", 0, "",
+		"build offline IMDB index from IMDB datasets (title.basics, title.episode, title.ratings) in a folder", "dir")
+	duplicatesFlag = getopt.BoolLong("duplicates", 0,
+		"list every file separately instead of merging media with the same identity")
+	getopt.IntVarLong(&minMediaSize, "min-size", 0, "skip video files smaller than this many MB (0 disables)", "MB")
+	getopt.ListVarLong(&includeGlobs, "include", 0,
+		"always score files matching glob patterns, overriding extras and size rules (ie. *-trailer.mkv)", "list")
+	getopt.ListVarLong(&excludeGlobs, "exclude", 0, "skip files and folders matching glob patterns (ie. *.iso,Anime)",
+		"list")
+
+	getopt.ListVarLong(&addExtensions, "add-ext", 0, "add permitted video extensions (ie. mp2,.divx)", "list")
+	getopt.ListVarLong(&removeExtensions, "remove-ext", 0, "remove permitted video extensions (ie. iso,ts)", "list")
+	getopt.BoolVarLong(&sniffVideo, "sniff", 0, "detect video files without permitted extension by their content")
+
+	// Permitted video extensions, in lowercase
+	videoExtensions = map[string]int{".3g2": 1, ".3gp": 1, ".3gp2": 1, ".asf": 1, ".avi": 1, ".divx": 1, ".f4v": 1,
+		".flv": 1, ".mk3d": 1, ".m2ts": 1, ".m4v": 1, ".mk2": 1, ".mkv": 1, ".mov": 1, ".mp4": 1, ".mpeg": 1, ".mpg": 1,
+		".mts": 1, ".ogg": 1, ".ogm": 1, ".ogv": 1, ".qt": 1, ".ram": 1, ".rm": 1, ".rmvb": 1, ".ts": 1, ".webm": 1,
+		".wmv": 1, ".iso": 1, ".vob": 1}
 
 	// Recognized env variables
 	omdbKey = os.Getenv("OMDB_API_KEY")
+	tmdbKey = os.Getenv("TMDB_API_KEY")
 	userCacheDir = os.Getenv("USER_CACHE_DIR")
 	_, ok := os.LookupEnv("DEBUG")
 	if ok {
@@ -82,15 +116,58 @@ func main() {
 	args := getopt.Args()
 
 	// Show usage
-	if *helpFlag || len(args) < 1 {
+	if *helpFlag || (len(args) < 1 && *importImdbFlag == "") {
 		getopt.PrintUsage(os.Stderr)
 		os.Exit(0)
 	}
 
+	// Offline IMDB index import, optionally followed by scoring
+	if *importImdbFlag != "" {
+		log.Infof("Importing IMDB datasets from %v, this might take a while.", *importImdbFlag)
+		if err := importOfflineIndex(*importImdbFlag); err != nil {
+			log.Errorf("Unable to import IMDB datasets: %v", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
+			os.Exit(0)
+		}
+	}
+
+	if err := checkGlobs(); err != nil {
+		log.Errorf("Unable to parse include/exclude patterns: %v", err)
+		os.Exit(1)
+	}
+	if err := setVideoExtensions(addExtensions, removeExtensions); err != nil {
+		log.Errorf("Unable to set video extensions: %v", err)
+		os.Exit(1)
+	}
+
+	// Cache maintenance requires neither OMDb access nor any other rating provider configuration
+	if args[0] == cacheCommand {
+		os.Exit(runCacheCommand(args[1:]))
+	}
+
+	// Enable rating providers and generate table columns from them
+	if err := enableProviders(*providersFlag); err != nil {
+		log.Errorf("Unable to enable rating providers: %v", err)
+		os.Exit(1)
+	}
+	tableTvHeader = append(append([]string{"Title", "Year", "Episode Title", "Season", "Episode Nr"},
+		ratingHeaders()...), matchHeader, filesHeader)
+	tableMovieHeader = append(append([]string{"Title", "Year"}, ratingHeaders()...), matchHeader, filesHeader)
+
 	// Require OMDb key: limit is 1k queries per day for a free tier
 	// Get yours here and/or donate: https://www.omdbapi.com/
-	if omdbKey == "" {
-		log.Error("Missing OMDb key. Please set OMDB_API_KEY environment variable.")
+	// Alternatively TMDb key can be used for media identity resolution: https://www.themoviedb.org/
+	// Offline mode requires neither, but needs offline IMDB index instead
+	if offlineMode {
+		if err := openOfflineIndex(); err != nil {
+			log.Errorf("Unable to open offline IMDB index: %v", err)
+			os.Exit(1)
+		}
+		defer closeOfflineIndex()
+	} else if omdbKey == "" && tmdbKey == "" {
+		log.Error("Missing OMDb key. Please set OMDB_API_KEY (or TMDB_API_KEY) environment variable.")
 		os.Exit(1)
 	}
 
@@ -114,12 +191,51 @@ func main() {
 	defer closeCache(cacheTv)
 	defer closeCache(cacheMovie)
 
+	// Output renderer selection: split output requires output file name to derive Movie and TV file names from
+	out := os.Stdout
+	var splitPath string
+	if *splitFlag {
+		if *outputFileFlag == "" {
+			log.Error("Separate Movie/TV files require output file name. Please set --output-file.")
+			os.Exit(1)
+		}
+		splitPath = *outputFileFlag
+	} else if *outputFileFlag != "" {
+		out, err = os.Create(*outputFileFlag)
+		if err != nil {
+			log.Errorf("Unable to create output file: %v", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	renderer, err := newRenderer(outputFormat, out, splitPath)
+	if err != nil {
+		log.Errorf("Unable to initialize output: %v", err)
+		os.Exit(1)
+	}
+
+	// Rating filters and sorting are applied before rendering
+	filterOpts, err := getFilterOptions()
+	if err != nil {
+		log.Errorf("Unable to parse filter options: %v", err)
+		os.Exit(1)
+	}
+	renderer = newFilterRenderer(renderer, filterOpts)
+
+	// Media found in several files is merged into a single entry, except for streamed output which cannot wait for
+	// duplicates to show up
+	if !*duplicatesFlag && outputFormat != outputNDJSON {
+		renderer = newDedupRenderer(renderer)
+	}
+
 	var wg sync.WaitGroup
 
 	// Spinning wheel
 	spinnerChan := make(chan struct{})
 
-	if !log.IsLevelEnabled(log.DebugLevel) {
+	// Spinner only makes sense for console tables as it would otherwise pollute machine-readable output
+	if !log.IsLevelEnabled(log.DebugLevel) && outputFormat == outputTable {
 		// Spinning wheel
 		wg.Add(1)
 		go func(channel <-chan struct{}) {
@@ -154,46 +270,38 @@ func main() {
 	go func(channel <-chan renderTable) {
 		defer wg.Done()
 
-		// Initialize TV and Movie table headers and style
-		var tvTableCtr, movieTableCtr int
-		tvTable := tvTableInit()
-		movieTable := movieTableInit()
-
 		for {
 			select {
 			case v, ok := <-channel:
 				// Start rendering when channel has been closed
 				if !ok {
-					// Render Movie table only if not empty
-					if movieTableCtr > 0 {
-						movieTable.Render()
-						if tvTableCtr > 0 {
-							fmt.Print("\n")
-						}
-					}
-					// Similarly render TV table only if not empty
-					if tvTableCtr > 0 {
-						tvTable.Render()
+					if err := renderer.Render(); err != nil {
+						log.Errorf("Unable to render output: %v", err)
 					}
 					return
 				}
 
-				// Reformat and push to appropriate table, caching only if needed
-				if v.data.IsTv {
-					data := []string{v.data.Title, v.data.Year, v.data.EpisodeTitle, v.data.Season, v.data.EpisodeNr,
-						v.data.ImdbRating, v.data.RtRating, v.data.McRating}
-					tvTable.Append(data)
-					tvTableCtr++
+				if err := renderer.Append(v); err != nil {
+					log.Errorf("Unable to render %v: %v", v.path, err)
+				}
 
-					if !v.isCached {
-						_ = updateCache(cacheTv, v)
+				// Diff goes to stderr so that it doesn't mix with machine-readable output
+				if *writeNfoFlag {
+					if err := writeNfo(v, *dryRunFlag, os.Stderr); err != nil {
+						log.Errorf("Unable to write .nfo for %v: %v", v.path, err)
 					}
-				} else {
-					data := []string{v.data.Title, v.data.Year, v.data.ImdbRating, v.data.RtRating, v.data.McRating}
-					movieTable.Append(data)
-					movieTableCtr++
+				}
 
-					if !v.isCached {
+				// Unmatched media has nothing to cache or write
+				if v.reason != "" {
+					continue
+				}
+
+				// Push to appropriate cache only if needed
+				if !v.isCached {
+					if v.data.IsTv {
+						_ = updateCache(cacheTv, v)
+					} else {
 						_ = updateCache(cacheMovie, v)
 					}
 				}
@@ -203,9 +311,10 @@ func main() {
 		}
 	}(renderChan)
 
-	// Process directories sequentially
+	// Process directories sequentially; hard links are tracked across all of them
+	links := make(map[string]bool)
 	for i := range args {
-		processDirectory(ctx, filepath.Clean(args[i]), renderChan)
+		processDirectory(ctx, filepath.Clean(args[i]), renderChan, links)
 	}
 
 	close(renderChan)
@@ -214,7 +323,7 @@ func main() {
 }
 
 // processDirectory processes each media folder, gets ranking data and sends it to rendering channel
-func processDirectory(ctx context.Context, rootPath string, renderChan chan renderTable) {
+func processDirectory(ctx context.Context, rootPath string, renderChan chan renderTable, links map[string]bool) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -262,18 +371,59 @@ func processDirectory(ctx context.Context, rootPath string, renderChan chan rend
 	}
 
 	// Fast concurrent directory walker: won't follow symlinks and won't sort entries
+	parts := make(map[string]bool)
 	err := godirwalk.Walk(rootPath, &godirwalk.Options{
 		Unsorted:            true,
 		FollowSymbolicLinks: false,
-		// Default callback processes only directory entries
+		// Default callback processes only directory entries, skipping over extras, samples and excluded media
 		Callback: func(osPathname string, de *godirwalk.Dirent) error {
+			if de.IsDir() && skipDir(rootPath, osPathname) {
+				log.Debugf("Skipping folder %v", osPathname)
+				return filepath.SkipDir
+			}
+
+			// DVD and Blu-ray disc structures are scored once as a whole
+			if de.IsDir() && isDiscDir(osPathname) {
+				fileChan <- osPathname
+				return filepath.SkipDir
+			}
+
 			// Process only if entry is filename
 			if de.IsRegular() {
-				_, err := os.Stat(osPathname)
+				fi, err := os.Stat(osPathname)
 				if err != nil {
 					return err
 				}
 
+				if reason := skipFile(rootPath, osPathname, fi); reason != "" {
+					log.Debugf("Skipping file %v: %v", osPathname, reason)
+					return nil
+				}
+
+				// Only video files can claim hard link and multi-part keys, otherwise subtitles or audio tracks
+				// could hide the actual video
+				if !isVideoFile(osPathname) {
+					return nil
+				}
+
+				// Hard links to already walked files are the same media
+				if key, ok := getFileKey(fi); ok {
+					if links[key] {
+						log.Debugf("Skipping file %v: hard link to already scored media", osPathname)
+						return nil
+					}
+					links[key] = true
+				}
+
+				// Multi-part media is scored only once, by its first part found
+				if key, ok := getPartKey(osPathname); ok {
+					if parts[key] {
+						log.Debugf("Skipping file %v: part of already scored media", osPathname)
+						return nil
+					}
+					parts[key] = true
+				}
+
 				fileChan <- osPathname
 			}
 			return nil
@@ -294,45 +444,119 @@ func processDirectory(ctx context.Context, rootPath string, renderChan chan rend
 	wg.Wait()
 }
 
-// getMovieInfo gets base name, checks if suffix is in recognized media suffixes, parses media information from the
-// filename and gets ratings
+// getMovieInfo gets base name of a video file or disc folder found by the directory walker, parses media information
+// from the filename and gets ratings
 func getMovieInfo(fullPath string, channel chan<- renderTable) {
-	baseName := filepath.Base(fullPath)
-	ext := filepath.Ext(baseName)
+	baseName := getMediaName(fullPath)
 
-	if _, ok := videoExtensions[ext]; ok {
-		info, err := parsetorrentname.Parse(baseName)
-		if err != nil {
-			log.Errorf("Not able to parse: %v", baseName)
+	info, err := parsetorrentname.Parse(stripImdbId(baseName))
+	if err != nil {
+		log.Errorf("Not able to parse: %v", baseName)
+	}
+
+	// Strip parsetorrentname() results from creeping trailing/leading dots
+	movieTitle := strings.Trim(info.Title, ".")
+	imdbID := getImdbIdFromPath(fullPath)
+
+	// TV series details missing from the filename are taken from parent folders
+	movieTitle, year, season, episode := inferFromDirs(fullPath, movieTitle, info.Year, info.Season, info.Episode)
+
+	// Kodi/Jellyfin .nfo identity takes precedence over filename parsing
+	if nfo, ok := getNfoInfo(fullPath); ok {
+		log.Debugf("Found .nfo for %v: %+v", baseName, nfo)
+		if nfo.title != "" {
+			movieTitle = nfo.title
+		}
+		if nfo.year != 0 {
+			year = nfo.year
+		}
+		if nfo.season != 0 && nfo.episode != 0 {
+			season, episode = nfo.season, nfo.episode
 		}
+		if nfo.imdbID != "" {
+			imdbID = nfo.imdbID
+		}
+	}
 
-		// Strip parsetorrentname() results from creeping trailing/leading dots
-		movieTitle := strings.Trim(info.Title, ".")
-		err = getRatings(baseName, movieTitle, info.Year, info.Season, info.Episode, channel)
+	// Daily shows have air date instead of season and episode, while their year is not the series year
+	airDate := ""
+	if season == 0 && episode == 0 {
+		if airDate = getAirDate(baseName); airDate != "" {
+			year = 0
+		}
+	}
+
+	// Multi-episode files result in one entry per episode
+	episodes := []int{episode}
+	if e := getEpisodes(baseName); len(e) > 1 && (season == 0 || e[0] == episode) {
+		episodes = e
+	}
+
+	for _, v := range episodes {
+		m := newMediaInfo(movieTitle, year, season, v)
+		m.imdbID = imdbID
+		m.airDate = airDate
+		m.multiEpisode = len(episodes) > 1
+		// Episode without season is either anime absolute episode number or a daily show
+		if season == 0 && (airDate != "" || isAbsoluteEpisode(baseName, year, v)) {
+			m.isTv = true
+			m.absolute = airDate == ""
+		}
+		// Episode number without season of media other than TV series is a misparsed year
+		if !m.isTv {
+			m.episode = 0
+		}
+
+		// Unmatched media is reported together with the reason instead of being silently dropped
+		unmatched := renderTable{path: fullPath, reason: reasonParse, data: CacheEntry{Title: movieTitle,
+			Year: zString(year), Season: zString(season), EpisodeNr: zString(m.episode), AirDate: airDate, IsTv: m.isTv}}
+		if movieTitle == "" && imdbID == "" {
+			log.Debugf("Unable to parse title from %v", baseName)
+			channel <- unmatched
+			return
+		}
+
+		err = getRatings(fullPath, m, channel)
 		if err != nil {
-			log.Debug("Unable to get ratings for %v: %v", baseName, err)
+			log.Debugf("Unable to get ratings for %v: %v", baseName, err)
+			unmatched.reason = unmatchedReason(err)
+			channel <- unmatched
 		}
 	}
 }
 
-// tvTableInit initializes TV table with header, formatting style, separator and borders
-func tvTableInit() *tablewriter.Table {
-	tvTable := tablewriter.NewWriter(os.Stdout)
-	tvTable.SetHeader(tableTvHeader)
-	tvTable.SetCaption(true, "TV Series Ratings ----------^")
-	tvTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-	tvTable.SetCenterSeparator("|")
+// getFilterOptions gathers sorting, combined score weights and rating threshold flags
+func getFilterOptions() (filterOptions, error) {
+	opts := filterOptions{sortKey: sortKey, order: sortOrder, excludeNA: *excludeNAFlag}
 
-	return tvTable
-}
+	weights, err := parseProviderValues(*weightsFlag)
+	if err != nil {
+		return opts, err
+	}
+	for k, v := range weights {
+		scoreWeights[k] = v
+	}
+
+	opts.minRatings, err = parseProviderValues(*minFlag)
+	if err != nil {
+		return opts, err
+	}
+
+	// Shorthand threshold flags for default rating providers
+	thresholds := []struct {
+		name, value string
+	}{{providerImdb, *minImdbFlag}, {providerRt, *minRtFlag}, {providerMc, *minMcFlag}}
+	for _, v := range thresholds {
+		if v.value == "" {
+			continue
+		}
 
-// movieTableInit initializes Movie table with header, formatting style, separator and borders
-func movieTableInit() *tablewriter.Table {
-	movieTable := tablewriter.NewWriter(os.Stdout)
-	movieTable.SetHeader(tableMovieHeader)
-	movieTable.SetCaption(true, "Movie Ratings ----------^")
-	movieTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-	movieTable.SetCenterSeparator("|")
+		f, ok := parseRating(v.value)
+		if !ok {
+			return opts, fmt.Errorf("invalid %v threshold: %v", v.name, v.value)
+		}
+		opts.minRatings[v.name] = f
+	}
 
-	return movieTable
+	return opts, nil
 }
@@ -0,0 +1,125 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markdownRenderer collects Movie and TV rows and renders them as GitHub-flavored Markdown tables
+type markdownRenderer struct {
+	w                              io.Writer
+	tvRows, movRows, unmatchedRows [][]string // Movie and TV rows have file paths as the last column
+	duplicates                     bool       // some media has been found in several files
+}
+
+// newMarkdownRenderer initializes Markdown renderer
+func newMarkdownRenderer(w io.Writer) *markdownRenderer {
+	return &markdownRenderer{w: w}
+}
+
+// Append formats media entry using the same columns as console tables
+func (m *markdownRenderer) Append(v renderTable) error {
+	e := labelledEntry(newOutputEntry(v))
+	if e.Reason != "" {
+		m.unmatchedRows = append(m.unmatchedRows, append(unmatchedRow(e), e.Path))
+	} else if e.IsTv {
+		m.tvRows = append(m.tvRows, append(tvRow(e), pathCell(e)))
+	} else {
+		m.movRows = append(m.movRows, append(movieRow(e), pathCell(e)))
+	}
+	m.duplicates = m.duplicates || e.Files > 1
+
+	return nil
+}
+
+// Render writes non-empty Movie and TV sections, with file paths column if any media has been found in several files
+func (m *markdownRenderer) Render() error {
+	if len(m.movRows) > 0 {
+		err := writeMarkdownTable(m.w, "Movie Ratings", pathHeader(tableMovieHeader, m.duplicates),
+			pathRows(m.movRows, m.duplicates))
+		if err != nil {
+			return err
+		}
+	}
+	if len(m.tvRows) > 0 {
+		if len(m.movRows) > 0 {
+			fmt.Fprint(m.w, "\n")
+		}
+		err := writeMarkdownTable(m.w, "TV Series Ratings", pathHeader(tableTvHeader, m.duplicates),
+			pathRows(m.tvRows, m.duplicates))
+		if err != nil {
+			return err
+		}
+	}
+	if len(m.unmatchedRows) > 0 {
+		if len(m.movRows) > 0 || len(m.tvRows) > 0 {
+			fmt.Fprint(m.w, "\n")
+		}
+		if err := writeMarkdownTable(m.w, "Unmatched", unmatchedHeader(), m.unmatchedRows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeMarkdownTable writes a section heading followed by a Markdown table
+func writeMarkdownTable(w io.Writer, title string, header []string, rows [][]string) error {
+	var sb strings.Builder
+
+	sb.WriteString("## " + title + "\n\n")
+	sb.WriteString(markdownRow(header))
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	sb.WriteString(markdownRow(sep))
+	for _, v := range rows {
+		sb.WriteString(markdownRow(v))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// pathRows returns Markdown table rows with file paths column removed unless requested
+func pathRows(rows [][]string, paths bool) [][]string {
+	res := make([][]string, len(rows))
+	for i, v := range rows {
+		res[i] = pathRow(v, paths)
+	}
+	return res
+}
+
+// markdownRow formats a single Markdown table row, escaping pipe characters in cells and breaking multi-line cells
+// (ie. several file paths) with HTML line breaks
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, v := range cells {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(v, "|", "\\|"), "\n", "<br>")
+	}
+
+	return "| " + strings.Join(escaped, " | ") + " |\n"
+}
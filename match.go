@@ -0,0 +1,99 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"net"
+	"strconv"
+)
+
+const confidenceExact = "exact"          // title and year match, or media has been looked up by IMDB Id
+const confidenceYearOff = "year ±1"      // title matches, but year is off by one (ie. release vs. RIP year)
+const confidenceTitleOnly = "title only" // title matches, but there is no year to compare
+const confidenceFallback = "fallback"    // title differs, or matched only through a fallback search or provider
+
+const reasonParse = "parse failure"   // no title could be parsed from the filename
+const reasonOmdb = "OMDb miss"        // OMDb has no media with a given title or IMDB Id
+const reasonImdb = "IMDb miss"        // IMDB title search has no media with a given title
+const reasonNetwork = "network error" // media could not be looked up due to network error
+const reasonNotFound = "not found"    // none of rating providers has found media
+
+// matchError is media lookup error with unmatched media reason
+type matchError struct {
+	reason string
+	err    error
+}
+
+// Error returns underlying error message
+func (e *matchError) Error() string {
+	return e.err.Error()
+}
+
+// unmatchedReason returns unmatched media reason for media lookup error; network errors take precedence
+func unmatchedReason(err error) string {
+	if me, ok := err.(*matchError); ok {
+		if _, ok := me.err.(net.Error); ok {
+			return reasonNetwork
+		}
+		return me.reason
+	}
+	if _, ok := err.(net.Error); ok {
+		return reasonNetwork
+	}
+
+	return reasonNotFound
+}
+
+// matchConfidence returns confidence of resolved media identity; TV series are compared by series title only (when
+// known) as resolved year is the episode air year
+func matchConfidence(m *mediaInfo) string {
+	if m.fallback {
+		return confidenceFallback
+	}
+	if m.byImdbID {
+		return confidenceExact
+	}
+
+	resolved := m.resolvedTitle
+	if m.isTv {
+		resolved = m.seriesTitle
+	}
+	if resolved != "" && normalizeTitle(resolved) != normalizeTitle(m.title) {
+		return confidenceFallback
+	}
+	if m.isTv || m.year == 0 {
+		return confidenceTitleOnly
+	}
+
+	year, err := strconv.Atoi(m.resolvedYear)
+	if err != nil {
+		year = dateYear(m.resolvedYear)
+	}
+	switch absInt(year - m.year) {
+	case 0:
+		return confidenceExact
+	case 1:
+		return confidenceYearOff
+	}
+
+	return confidenceFallback
+}
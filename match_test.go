@@ -0,0 +1,89 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchConfidence(t *testing.T) {
+	tests := []struct {
+		name       string
+		m          mediaInfo
+		confidence string
+	}{
+		{"title and year", mediaInfo{title: "Heat", year: 1995, resolvedTitle: "Heat", resolvedYear: "1995"},
+			confidenceExact},
+		{"punctuation and case", mediaInfo{title: "Mission Impossible", year: 1996,
+			resolvedTitle: "Mission: Impossible", resolvedYear: "1996"}, confidenceExact},
+		{"year off by one", mediaInfo{title: "Heat", year: 1996, resolvedTitle: "Heat", resolvedYear: "1995"},
+			confidenceYearOff},
+		{"year off by more", mediaInfo{title: "Heat", year: 1986, resolvedTitle: "Heat", resolvedYear: "1995"},
+			confidenceFallback},
+		{"no year", mediaInfo{title: "Heat", resolvedTitle: "Heat", resolvedYear: "1995"}, confidenceTitleOnly},
+		{"different title", mediaInfo{title: "Heat", year: 1995, resolvedTitle: "Heat Wave", resolvedYear: "1995"},
+			confidenceFallback},
+		{"different title without year", mediaInfo{title: "Heat", resolvedTitle: "Heat Wave"}, confidenceFallback},
+		{"IMDB Id", mediaInfo{title: "Whatever", byImdbID: true, resolvedTitle: "Heat", resolvedYear: "1995"},
+			confidenceExact},
+		{"fallback", mediaInfo{title: "Heat", year: 1995, resolvedTitle: "Heat", resolvedYear: "1995",
+			fallback: true}, confidenceFallback},
+		{"TV series", mediaInfo{title: "Friends", isTv: true, season: 1, episode: 2, seriesTitle: "Friends",
+			resolvedTitle: "The One with the Sonogram at the End"}, confidenceTitleOnly},
+		{"TV series without series title", mediaInfo{title: "Friends", isTv: true, season: 1, episode: 2,
+			resolvedTitle: "The One with the Sonogram at the End"}, confidenceTitleOnly},
+		{"different TV series", mediaInfo{title: "Friends", isTv: true, season: 1, episode: 2,
+			seriesTitle: "Friends with Benefits"}, confidenceFallback},
+	}
+
+	for _, tt := range tests {
+		if got := matchConfidence(&tt.m); got != tt.confidence {
+			t.Errorf("%v: matchConfidence() = %q, want %q", tt.name, got, tt.confidence)
+		}
+	}
+}
+
+func TestUnmatchedReason(t *testing.T) {
+	// TVmaze stand-in which is not listening anymore results in network error
+	ts := httptest.NewServer(http.NotFoundHandler())
+	oldUrl := tvmazeBaseUrl
+	defer func() { tvmazeBaseUrl = oldUrl }()
+	tvmazeBaseUrl = ts.URL
+
+	_, err := tvmazeSearch("Friends")
+	if got := unmatchedReason(err); got != reasonNotFound {
+		t.Errorf("unmatchedReason() of HTTP 404 = %q, want %q", got, reasonNotFound)
+	}
+
+	ts.Close()
+	_, err = tvmazeSearch("Friends")
+	if got := unmatchedReason(err); got != reasonNetwork {
+		t.Errorf("unmatchedReason() of closed connection = %q, want %q (%v)", got, reasonNetwork, err)
+	}
+
+	if got := unmatchedReason(&matchError{reason: reasonOmdb, err: errors.New("movie not found")}); got != reasonOmdb {
+		t.Errorf("unmatchedReason() of OMDb miss = %q, want %q", got, reasonOmdb)
+	}
+}
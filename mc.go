@@ -24,6 +24,7 @@ package main
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -34,8 +35,70 @@ const mcResultLink = ".result a[href]"                       // search page resu
 const mcMetaScoreSelector = ".phead_summary .metascore_w"    // MC Metascore
 const mcUserScoreSelector = ".metascore_w.user"              // MC Userscore
 
-// getMcScore initiates MetaScore search for media, gets MetaScore and if it is not available yet gets UserScore
-func getMcScore(mediaTitle, omdbTitle string, mediaYear, mediaSeason, mediaEpisode int, isTv bool) (string, error) {
+// mcProvider fetches Metacritic Metascore (critics score) by scraping Metacritic search and media pages
+type mcProvider struct{}
+
+// Name returns Metacritic Metascore provider name
+func (p *mcProvider) Name() string { return providerMc }
+
+// Header returns Metacritic Metascore table column header
+func (p *mcProvider) Header() string { return "Metascore" }
+
+// Scale returns maximum Metacritic Metascore
+func (p *mcProvider) Scale() float64 { return 100 }
+
+// Resolve is not supported by Metacritic provider
+func (p *mcProvider) Resolve(m *mediaInfo) error { return errNoResolver }
+
+// Rating scrapes Metacritic Metascore
+func (p *mcProvider) Rating(m *mediaInfo) (string, error) {
+	return getMcRating(m, providerMc)
+}
+
+// mcUserProvider fetches Metacritic User Score by scraping Metacritic search and media pages
+type mcUserProvider struct{}
+
+// Name returns Metacritic User Score provider name
+func (p *mcUserProvider) Name() string { return providerMcUser }
+
+// Header returns Metacritic User Score table column header
+func (p *mcUserProvider) Header() string { return "MC User Score" }
+
+// Scale returns maximum Metacritic User Score
+func (p *mcUserProvider) Scale() float64 { return 10 }
+
+// Resolve is not supported by Metacritic provider
+func (p *mcUserProvider) Resolve(m *mediaInfo) error { return errNoResolver }
+
+// Rating scrapes Metacritic User Score
+func (p *mcUserProvider) Rating(m *mediaInfo) (string, error) {
+	return getMcRating(m, providerMcUser)
+}
+
+// getMcRating returns already known Metacritic score, or scrapes Metacritic media page once for both Metascore and
+// User Score
+func getMcRating(m *mediaInfo, name string) (string, error) {
+	if v, ok := m.known[name]; ok {
+		return v, nil
+	}
+
+	metaScore, userScore, err := getMcScore(m.title, m.resolvedTitle, m.year, m.season, m.episode, m.isTv)
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range map[string]string{providerMc: metaScore, providerMcUser: userScore} {
+		if _, ok := m.known[k]; !ok {
+			m.known[k] = v
+		}
+	}
+
+	return m.known[name], nil
+}
+
+// getMcScore initiates Metacritic search for media and gets both MetaScore and UserScore
+func getMcScore(mediaTitle, omdbTitle string, mediaYear, mediaSeason, mediaEpisode int, isTv bool) (string, string,
+	error) {
 	// Always generate MC URL as OMDb doesn't provide it
 	var mcUrl string
 	if isTv {
@@ -46,7 +109,7 @@ func getMcScore(mediaTitle, omdbTitle string, mediaYear, mediaSeason, mediaEpiso
 
 	res, doc, err := getMediaDoc(mcUrl, mcRefUrl)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer res.Body.Close()
 
@@ -54,7 +117,7 @@ func getMcScore(mediaTitle, omdbTitle string, mediaYear, mediaSeason, mediaEpiso
 	str, ok := doc.Find(mcResultLink).First().Attr("href")
 	if !ok {
 		log.Debugf("Unable to find Metacritic movie page, used search query: %v", mcUrl)
-		return "N/A", nil
+		return "N/A", "N/A", nil
 	}
 	mcUrl = mcBaseUrl + str
 	// If it's TV then append relevant season
@@ -64,32 +127,27 @@ func getMcScore(mediaTitle, omdbTitle string, mediaYear, mediaSeason, mediaEpiso
 
 	res2, doc, err := getMediaDoc(mcUrl, mcRefUrl)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer res2.Body.Close()
 
 	// Find MetaScore rating
-	rating := doc.Find(mcMetaScoreSelector).First().Text()
-	if rating == "" || !isInt(rating) {
-		// If we don't have MetaScore, extract UserScore
-		userRating := doc.Find(mcUserScoreSelector).First().Text()
-		if len(userRating) > 0 {
-			f, err := strconv.ParseFloat(userRating, 32)
-			if err != nil {
-				return "N/A", nil
-			}
-
-			// At least emulate the same range as MetaScore
-			userRating = fmt.Sprintf("%d", int(f*10))
-
-			return userRating, nil
-		}
+	metaScore := strings.TrimSpace(doc.Find(mcMetaScoreSelector).First().Text())
+	if metaScore == "" || !isInt(metaScore) {
+		metaScore = "N/A"
+	}
+
+	// Find UserScore rating, which is on 0-10 scale
+	userScore := strings.TrimSpace(doc.Find(mcUserScoreSelector).First().Text())
+	if _, err := strconv.ParseFloat(userScore, 32); err != nil {
+		userScore = "N/A"
+	}
 
+	if metaScore == "N/A" && userScore == "N/A" {
 		log.Debugf("Unable to find Metacritic score (metascore or userscore), used media page: %v", mcUrl)
-		return "N/A", nil
 	}
 
-	return rating, nil
+	return metaScore, userScore, nil
 }
 
 // getMcYearRange creates a MC search filter with +1 year from a given date
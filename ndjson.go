@@ -0,0 +1,47 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ndjsonRenderer streams each media entry as a single JSON line as soon as it has been scored
+type ndjsonRenderer struct {
+	enc *json.Encoder
+}
+
+// newNDJSONRenderer initializes NDJSON renderer
+func newNDJSONRenderer(w io.Writer) *ndjsonRenderer {
+	return &ndjsonRenderer{enc: json.NewEncoder(w)}
+}
+
+// Append immediately writes media entry as a newline-terminated JSON object
+func (n *ndjsonRenderer) Append(v renderTable) error {
+	return n.enc.Encode(newOutputEntry(v))
+}
+
+// Render does nothing as all entries have already been written
+func (n *ndjsonRenderer) Render() error {
+	return nil
+}
@@ -0,0 +1,167 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const nfoExt = ".nfo"                   // Kodi/Jellyfin media information sidecar extension
+const nfoMovie = "movie.nfo"            // Movie .nfo in a per-movie folder
+const nfoTvShow = "tvshow.nfo"          // TV series .nfo in series folder
+const nfoRootMovie = "movie"            // Movie .nfo root element
+const nfoRootEpisode = "episodedetails" // TV series episode .nfo root element
+const nfoUniqueIDImdb = "imdb"          // uniqueid type holding IMDB Id
+
+// nfoImdbIdRegexp matches a plain IMDB Id in legacy <id> element
+var nfoImdbIdRegexp = regexp.MustCompile(`^tt\d{7,}$`)
+
+// nfoUniqueID is Kodi .nfo <uniqueid type="imdb"> element
+type nfoUniqueID struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// nfoDetails holds .nfo fields relevant for media identity, shared by <movie>, <episodedetails> and <tvshow>
+type nfoDetails struct {
+	XMLName   xml.Name
+	Title     string        `xml:"title"`
+	ShowTitle string        `xml:"showtitle"`
+	Year      string        `xml:"year"`
+	Premiered string        `xml:"premiered"`
+	Season    string        `xml:"season"`
+	Episode   string        `xml:"episode"`
+	UniqueIDs []nfoUniqueID `xml:"uniqueid"`
+	ImdbID    string        `xml:"imdbid"`
+	ID        string        `xml:"id"`
+}
+
+// nfoInfo holds media identity gathered from .nfo files; zero values are unknown
+type nfoInfo struct {
+	title   string
+	year    int
+	season  int
+	episode int
+	imdbID  string
+}
+
+// getNfoInfo looks for Kodi/Jellyfin .nfo files next to the media file (<basename>.nfo or movie.nfo) and for
+// tvshow.nfo in media folder or up to two parent folders, returning media identity found in them
+func getNfoInfo(fullPath string) (nfoInfo, bool) {
+	dir := filepath.Dir(fullPath)
+	base := strings.TrimSuffix(filepath.Base(fullPath), filepath.Ext(fullPath))
+	if titleDir, ok := getTitleDir(fullPath); ok {
+		// Disc structures and multi-part media have movie.nfo in their title folder
+		dir, base = titleDir, strings.TrimSuffix(nfoMovie, nfoExt)
+	}
+
+	media, mediaOk := readNfoFirst(filepath.Join(dir, base+nfoExt), filepath.Join(dir, nfoMovie))
+	if mediaOk && media.XMLName.Local == nfoRootMovie {
+		return nfoInfo{title: media.Title, year: media.year(), imdbID: media.imdbID()}, true
+	}
+
+	show, showOk := readNfoFirst(filepath.Join(dir, nfoTvShow), filepath.Join(filepath.Dir(dir), nfoTvShow),
+		filepath.Join(filepath.Dir(filepath.Dir(dir)), nfoTvShow))
+	if !mediaOk && !showOk {
+		return nfoInfo{}, false
+	}
+
+	var info nfoInfo
+	if mediaOk && media.XMLName.Local == nfoRootEpisode {
+		info = nfoInfo{title: media.ShowTitle, season: atoiOrZero(strings.TrimSpace(media.Season)),
+			episode: atoiOrZero(strings.TrimSpace(media.Episode)),
+			imdbID:  media.imdbID()}
+	}
+
+	// TV series .nfo has series title, year and IMDB Id, which are better suited for TV series lookups than IMDB Id
+	// of the episode itself
+	if showOk {
+		if show.Title != "" {
+			info.title = show.Title
+		}
+		info.year = show.year()
+		if id := show.imdbID(); id != "" {
+			info.imdbID = id
+		}
+	}
+
+	return info, true
+}
+
+// readNfoFirst reads the first existing and valid .nfo file from a list of file names
+func readNfoFirst(names ...string) (nfoDetails, bool) {
+	for _, name := range names {
+		if d, err := readNfo(name); err == nil {
+			return d, true
+		}
+	}
+	return nfoDetails{}, false
+}
+
+// readNfo decodes the first XML element of .nfo file; Kodi also permits a scraper URL following XML content, which is
+// ignored
+func readNfo(name string) (nfoDetails, error) {
+	var d nfoDetails
+
+	file, err := os.Open(name)
+	if err != nil {
+		return d, err
+	}
+	defer file.Close()
+
+	dec := xml.NewDecoder(file)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	err = dec.Decode(&d)
+
+	return d, err
+}
+
+// year returns .nfo year, or premiered year if year is missing
+func (d nfoDetails) year() int {
+	if y, err := strconv.Atoi(strings.TrimSpace(d.Year)); err == nil && y > 0 {
+		return y
+	}
+	return dateYear(strings.TrimSpace(d.Premiered))
+}
+
+// imdbID returns IMDB Id from <uniqueid type="imdb">, or from legacy <imdbid> and <id> elements
+func (d nfoDetails) imdbID() string {
+	for _, v := range d.UniqueIDs {
+		if strings.EqualFold(v.Type, nfoUniqueIDImdb) && strings.TrimSpace(v.Value) != "" {
+			return strings.TrimSpace(v.Value)
+		}
+	}
+	if id := strings.TrimSpace(d.ImdbID); id != "" {
+		return id
+	}
+	if id := strings.TrimSpace(d.ID); nfoImdbIdRegexp.MatchString(id) {
+		return id
+	}
+	return ""
+}
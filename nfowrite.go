@@ -0,0 +1,302 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const nfoPerm = 0644           // permissions of newly created .nfo files
+const nfoIndent = "    "       // default .nfo indentation, as written by Kodi
+const nfoNewFile = "/dev/null" // diff name for files which do not exist yet
+const nfoHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>`
+
+// nfoRatingNames maps rating provider names to Kodi rating names
+var nfoRatingNames = map[string]string{providerImdb: "imdb", providerRt: "tomatometerallcritics",
+	providerRtAudience: "tomatometerallaudience", providerMc: "metacritic", providerMcUser: "metacriticuser",
+	providerTmdb: "themoviedb", providerTvmaze: "tvmaze"}
+
+var nfoRatingsRegexp = regexp.MustCompile(`(?s)<ratings\s*>(.*?)</ratings\s*>`)
+var nfoEmptyRatingsRegexp = regexp.MustCompile(`<ratings\s*/>`)
+var nfoValueRegexp = regexp.MustCompile(`(?s)<value\s*>.*?</value\s*>`)
+var nfoIndentRegexp = regexp.MustCompile(`\n([ \t]+)<`)
+
+// nfoRating is a single Kodi .nfo rating
+type nfoRating struct {
+	name  string
+	max   float64
+	value string
+}
+
+// writeNfo writes ratings of enabled rating providers into Kodi .nfo sidecar of a media file, updating existing
+// ratings while keeping all other .nfo content intact; in dry-run mode changes are only written to w as a diff
+func writeNfo(v renderTable, dryRun bool, w io.Writer) error {
+	// Episodes of multi-episode file share a single .nfo, where each of them would overwrite ratings of the others
+	if v.data.IsTv && getEpisodes(getMediaName(v.path)) != nil {
+		log.Debugf("Skipping .nfo of multi-episode file %v", v.path)
+		return nil
+	}
+
+	ratings := getNfoRatings(v.data)
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	name, exists := getNfoPath(v.path, v.data.IsTv)
+	var oldText, newText string
+	perm := os.FileMode(nfoPerm)
+	if exists {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		perm = fi.Mode().Perm()
+
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		oldText = string(b)
+
+		newText, err = updateNfoRatings(oldText, ratings)
+		if err != nil {
+			return fmt.Errorf("unable to update %v: %v", name, err)
+		}
+	} else {
+		newText = newNfo(v.data, ratings)
+	}
+
+	if newText == oldText {
+		return nil
+	}
+
+	if dryRun {
+		oldName := name
+		if !exists {
+			oldName = nfoNewFile
+		}
+		_, err := io.WriteString(w, unifiedDiff(oldName, name, oldText, newText))
+		return err
+	}
+
+	return ioutil.WriteFile(name, []byte(newText), perm)
+}
+
+// getNfoPath returns existing .nfo sidecar of a media file (<basename>.nfo, or movie.nfo for Movies), or
+// <basename>.nfo if there is none
+func getNfoPath(fullPath string, isTv bool) (string, bool) {
+	// Disc structures and multi-part media use movie.nfo in their title folder
+	if dir, ok := getTitleDir(fullPath); ok && !isTv {
+		name := filepath.Join(dir, nfoMovie)
+		_, err := os.Stat(name)
+		return name, err == nil
+	}
+
+	dir := filepath.Dir(fullPath)
+	name := filepath.Join(dir, strings.TrimSuffix(filepath.Base(fullPath), filepath.Ext(fullPath))+nfoExt)
+
+	candidates := []string{name}
+	if !isTv {
+		candidates = append(candidates, filepath.Join(dir, nfoMovie))
+	}
+	for _, v := range candidates {
+		if fi, err := os.Stat(v); err == nil && fi.Mode().IsRegular() {
+			return v, true
+		}
+	}
+
+	return name, false
+}
+
+// getNfoRatings returns valid ratings of enabled rating providers, skipping N/A
+func getNfoRatings(data CacheEntry) []nfoRating {
+	var ratings []nfoRating
+	for _, p := range enabledProviders {
+		f, ok := parseRating(data.Ratings[p.Name()])
+		if !ok {
+			continue
+		}
+
+		name, ok := nfoRatingNames[p.Name()]
+		if !ok {
+			name = p.Name()
+		}
+		// Keep the original precision of plain numeric ratings (ie. "9.0")
+		value := strings.TrimSpace(data.Ratings[p.Name()])
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			value = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		ratings = append(ratings, nfoRating{name: name, max: p.Scale(), value: value})
+	}
+
+	return ratings
+}
+
+// updateNfoRatings updates existing .nfo content: values of existing ratings are replaced keeping their other
+// attributes and elements (ie. default and votes), missing ratings are added to <ratings> and <ratings> is added to the
+// root element if there is none
+func updateNfoRatings(text string, ratings []nfoRating) (string, error) {
+	unit := nfoIndent
+	if res := nfoIndentRegexp.FindStringSubmatch(text); res != nil {
+		unit = res[1]
+	}
+
+	// Expand empty <ratings/> so that it can be updated as usual
+	if loc := nfoEmptyRatingsRegexp.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]] + "<ratings></ratings>" + text[loc[1]:]
+	}
+
+	loc := nfoRatingsRegexp.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return insertNfoRatings(text, ratings, unit)
+	}
+
+	indent := lineIndent(text, loc[0])
+	block := text[loc[2]:loc[3]]
+	for _, r := range ratings {
+		// Self-closing <rating .../> has to be matched on its own, otherwise it would extend to the next </rating>
+		re := regexp.MustCompile(`(?s)<rating\b[^>]*\bname="` + regexp.QuoteMeta(r.name) +
+			`"[^>]*?(?:/>|>.*?</rating\s*>)`)
+		if eloc := re.FindStringIndex(block); eloc != nil {
+			elem := block[eloc[0]:eloc[1]]
+			if strings.HasSuffix(elem, "/>") {
+				elem = strings.TrimRight(strings.TrimSuffix(elem, "/>"), " \t\r\n") + "><value>" + r.value +
+					"</value></rating>"
+			} else if nfoValueRegexp.MatchString(elem) {
+				elem = nfoValueRegexp.ReplaceAllLiteralString(elem, "<value>"+r.value+"</value>")
+			} else {
+				end := strings.LastIndex(elem, "</rating")
+				elem = elem[:end] + "<value>" + r.value + "</value>" + elem[end:]
+			}
+			block = block[:eloc[0]] + elem + block[eloc[1]:]
+			continue
+		}
+
+		elem := formatNfoRating(r, indent+unit, unit)
+		if strings.TrimSpace(block) == "" {
+			block = "\n" + indent + unit + elem + "\n" + indent
+		} else {
+			end := len(strings.TrimRight(block, " \t\r\n"))
+			block = block[:end] + "\n" + indent + unit + elem + block[end:]
+		}
+	}
+
+	return text[:loc[2]] + block + text[loc[3]:], nil
+}
+
+// insertNfoRatings adds <ratings> as the last child of .nfo root element
+func insertNfoRatings(text string, ratings []nfoRating, unit string) (string, error) {
+	var root xml.Name
+	dec := xml.NewDecoder(strings.NewReader(text))
+	dec.Strict = false
+	for root.Local == "" {
+		t, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("unable to find root element: %v", err)
+		}
+		if v, ok := t.(xml.StartElement); ok {
+			root = v.Name
+		}
+	}
+
+	end := strings.LastIndex(text, "</"+root.Local)
+	if end < 0 {
+		return "", fmt.Errorf("unable to find closing %v element", root.Local)
+	}
+
+	elem := formatNfoRatings(ratings, unit, unit)
+	start := strings.LastIndex(text[:end], "\n") + 1
+	if strings.TrimSpace(text[start:end]) == "" {
+		// Closing root element on its own line
+		return text[:start] + unit + elem + "\n" + text[start:], nil
+	}
+	return text[:end] + "\n" + unit + elem + "\n" + text[end:], nil
+}
+
+// newNfo returns a minimal Kodi .nfo for Movie or TV series episode holding media identity and ratings
+func newNfo(data CacheEntry, ratings []nfoRating) string {
+	var b strings.Builder
+	b.WriteString(nfoHeader + "\n")
+
+	root := nfoRootMovie
+	fields := [][2]string{{"title", data.Title}, {"year", data.Year}}
+	if data.IsTv {
+		root = nfoRootEpisode
+		fields = [][2]string{{"title", data.EpisodeTitle}, {"showtitle", data.Title}, {"season", data.Season},
+			{"episode", data.EpisodeNr}, {"aired", data.AirDate}}
+	}
+
+	fmt.Fprintf(&b, "<%v>\n", root)
+	for _, v := range fields {
+		if v[1] != "" {
+			fmt.Fprintf(&b, "%v<%v>%v</%v>\n", nfoIndent, v[0], xmlEscape(v[1]), v[0])
+		}
+	}
+	if data.ImdbID != "" {
+		fmt.Fprintf(&b, "%v<uniqueid type=\"%v\">%v</uniqueid>\n", nfoIndent, nfoUniqueIDImdb, xmlEscape(data.ImdbID))
+	}
+	fmt.Fprintf(&b, "%v%v\n</%v>\n", nfoIndent, formatNfoRatings(ratings, nfoIndent, nfoIndent), root)
+
+	return b.String()
+}
+
+// formatNfoRatings formats <ratings> element with given indentation of the element itself and indentation unit
+func formatNfoRatings(ratings []nfoRating, indent, unit string) string {
+	var b strings.Builder
+	b.WriteString("<ratings>")
+	for _, r := range ratings {
+		b.WriteString("\n" + indent + unit + formatNfoRating(r, indent+unit, unit))
+	}
+	b.WriteString("\n" + indent + "</ratings>")
+
+	return b.String()
+}
+
+// formatNfoRating formats a single <rating> element with given indentation of the element itself and indentation unit
+func formatNfoRating(r nfoRating, indent, unit string) string {
+	return fmt.Sprintf("<rating name=\"%v\" max=\"%v\">\n%v%v<value>%v</value>\n%v</rating>", xmlEscape(r.name),
+		strconv.FormatFloat(r.max, 'f', -1, 64), indent, unit, r.value, indent)
+}
+
+// lineIndent returns whitespace indentation of the line containing a given position
+func lineIndent(text string, pos int) string {
+	line := text[strings.LastIndex(text[:pos], "\n")+1 : pos]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// xmlEscape escapes text for use in XML character data and attributes
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,150 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import "testing"
+
+func TestUpdateNfoRatings(t *testing.T) {
+	ratings := []nfoRating{{name: "imdb", max: 10, value: "8.1"}, {name: "themoviedb", max: 10, value: "7.9"}}
+
+	tests := []struct {
+		name, text, want string
+	}{
+		{
+			name: "existing rating with default and votes",
+			text: `<movie>
+    <title>Heat</title>
+    <ratings>
+        <rating name="imdb" max="10" default="true">
+            <value>7.5</value>
+            <votes>1000</votes>
+        </rating>
+    </ratings>
+</movie>
+`,
+			want: `<movie>
+    <title>Heat</title>
+    <ratings>
+        <rating name="imdb" max="10" default="true">
+            <value>8.1</value>
+            <votes>1000</votes>
+        </rating>
+        <rating name="themoviedb" max="10">
+            <value>7.9</value>
+        </rating>
+    </ratings>
+</movie>
+`,
+		},
+		{
+			name: "missing ratings",
+			text: `<movie>
+  <title>Heat</title>
+</movie>
+`,
+			want: `<movie>
+  <title>Heat</title>
+  <ratings>
+    <rating name="imdb" max="10">
+      <value>8.1</value>
+    </rating>
+    <rating name="themoviedb" max="10">
+      <value>7.9</value>
+    </rating>
+  </ratings>
+</movie>
+`,
+		},
+		{
+			name: "missing ratings with closing root element on the same line",
+			text: `<movie><title>Heat</title></movie>`,
+			want: `<movie><title>Heat</title>
+    <ratings>
+        <rating name="imdb" max="10">
+            <value>8.1</value>
+        </rating>
+        <rating name="themoviedb" max="10">
+            <value>7.9</value>
+        </rating>
+    </ratings>
+</movie>`,
+		},
+		{
+			name: "empty ratings",
+			text: `<movie>
+    <title>Heat</title>
+    <ratings/>
+</movie>
+`,
+			want: `<movie>
+    <title>Heat</title>
+    <ratings>
+        <rating name="imdb" max="10">
+            <value>8.1</value>
+        </rating>
+        <rating name="themoviedb" max="10">
+            <value>7.9</value>
+        </rating>
+    </ratings>
+</movie>
+`,
+		},
+		{
+			name: "self-closing rating next to a named one",
+			text: `<movie>
+    <ratings>
+        <rating name="imdb" max="10"/>
+        <rating name="themoviedb" max="10">
+            <value>6.0</value>
+        </rating>
+    </ratings>
+</movie>
+`,
+			want: `<movie>
+    <ratings>
+        <rating name="imdb" max="10"><value>8.1</value></rating>
+        <rating name="themoviedb" max="10">
+            <value>7.9</value>
+        </rating>
+    </ratings>
+</movie>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := updateNfoRatings(tt.text, ratings)
+		if err != nil {
+			t.Errorf("%v: updateNfoRatings() error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v: updateNfoRatings() =\n%v\nwant\n%v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateNfoRatingsInvalid(t *testing.T) {
+	if _, err := updateNfoRatings("not an XML file", []nfoRating{{name: "imdb", max: 10, value: "8.1"}}); err == nil {
+		t.Error("updateNfoRatings() of invalid .nfo succeeded, want error")
+	}
+}
@@ -0,0 +1,493 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const offlineFolder = "MediaScoreOffline"              // kept apart from cache folder so that cleaning cache keeps it
+const offlineIndexName = "imdb.db"                     // offline IMDB index database
+const offlineBatchSize = 100000                        // dataset rows written per index transaction
+const offlineLineSize = 1024 * 1024                    // maximum dataset line length
+const imdbBasicsFile = "title.basics.tsv.gz"           // IMDB dataset: titles
+const imdbEpisodesFile = "title.episode.tsv.gz"        // IMDB dataset: TV series episodes
+const imdbRatingsFile = "title.ratings.tsv.gz"         // IMDB dataset: ratings
+const imdbDatasetsURL = "https://datasets.imdbws.com/" // IMDB datasets download location
+const imdbNullValue = "\\N"                            // IMDB dataset empty value
+const offlineOpenTimeout = time.Second                 // wait for index lock at most 1s
+
+var offlineMode bool      // resolve media and IMDB ratings from offline IMDB index only
+var offlineIndex *bolt.DB // offline IMDB index opened by openOfflineIndex()
+
+var bucketTitles = []byte("titles")     // IMDB Id -> offlineTitle
+var bucketNames = []byte("names")       // normalized title + NUL + IMDB Id -> empty
+var bucketEpisodes = []byte("episodes") // TV series IMDB Id + NUL + season + NUL + episode -> episode IMDB Id
+
+// offlineTitle holds a single title from offline IMDB index
+type offlineTitle struct {
+	id       string
+	kind     string // IMDB title type, ie. "movie", "tvSeries" or "tvEpisode"
+	title    string
+	year     int
+	rating   string
+	numVotes int
+}
+
+// offlineProvider is implemented by rating providers able to work without network access
+type offlineProvider interface {
+	Offline() bool
+}
+
+// getOfflineDir returns offline IMDB index folder, next to the cache folder
+func getOfflineDir() (string, error) {
+	// getCacheDir() initializes userCacheDir as well
+	if _, err := getCacheDir(); err != nil {
+		return "", err
+	}
+
+	return userCacheDir + string(os.PathSeparator) + offlineFolder, nil
+}
+
+// openOfflineIndex opens existing offline IMDB index read-only
+func openOfflineIndex() error {
+	subDir, err := getOfflineDir()
+	if err != nil {
+		return err
+	}
+
+	name := subDir + string(os.PathSeparator) + offlineIndexName
+	if _, err := os.Stat(name); err != nil {
+		return fmt.Errorf("offline IMDB index not found, please import IMDB datasets first: %v", err)
+	}
+
+	offlineIndex, err = bolt.Open(name, 0600, &bolt.Options{ReadOnly: true, Timeout: offlineOpenTimeout})
+	return err
+}
+
+// closeOfflineIndex closes offline IMDB index if it has been opened
+func closeOfflineIndex() {
+	if offlineIndex != nil {
+		_ = offlineIndex.Close()
+	}
+}
+
+// importOfflineIndex builds offline IMDB index from title.basics, title.episode and title.ratings dataset files found
+// in a given folder; existing index is replaced only once import has been successful
+func importOfflineIndex(dir string) error {
+	subDir, err := getOfflineDir()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(subDir, cachePerm)
+	if err != nil {
+		return err
+	}
+
+	name := subDir + string(os.PathSeparator) + offlineIndexName
+	tmpName := name + ".tmp"
+	_ = os.Remove(tmpName)
+
+	db, err := bolt.Open(tmpName, 0600, &bolt.Options{Timeout: offlineOpenTimeout, NoSync: true})
+	if err != nil {
+		return err
+	}
+
+	err = buildOfflineIndex(db, dir)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+
+	return os.Rename(tmpName, name)
+}
+
+// buildOfflineIndex ingests IMDB datasets into offline IMDB index: ratings are held in memory and merged with titles,
+// and TV series episodes are linked to their series afterwards
+func buildOfflineIndex(db *bolt.DB, dir string) error {
+	ratings := make(map[string]string)
+	err := readDataset(dir+string(os.PathSeparator)+imdbRatingsFile, func(f []string) error {
+		if len(f) < 3 {
+			return nil
+		}
+		ratings[f[0]] = f[1] + "\t" + f[2]
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("Loaded %d ratings from %v", len(ratings), imdbRatingsFile)
+
+	count := 0
+	err = writeDataset(db, dir+string(os.PathSeparator)+imdbBasicsFile, func(tx *bolt.Tx, f []string) error {
+		if len(f) < 6 || f[1] == "videoGame" {
+			return nil
+		}
+
+		rating := imdbNullValue + "\t0"
+		if v, ok := ratings[f[0]]; ok {
+			rating = v
+		}
+
+		titles := tx.Bucket(bucketTitles)
+		titles.FillPercent = 1
+		err := titles.Put([]byte(f[0]), []byte(strings.Join([]string{f[1], f[2], f[5], rating}, "\t")))
+		if err != nil {
+			return err
+		}
+		count++
+
+		// Episodes are looked up through their TV series only
+		if f[1] == "tvEpisode" {
+			return nil
+		}
+		names := tx.Bucket(bucketNames)
+		for _, v := range []string{f[2], f[3]} {
+			if n := normalizeTitle(v); n != "" {
+				if err := names.Put([]byte(n+"\x00"+f[0]), nil); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("Imported %d titles from %v", count, imdbBasicsFile)
+
+	count = 0
+	err = writeDataset(db, dir+string(os.PathSeparator)+imdbEpisodesFile, func(tx *bolt.Tx, f []string) error {
+		if len(f) < 4 || f[2] == imdbNullValue || f[3] == imdbNullValue {
+			return nil
+		}
+
+		count++
+		return tx.Bucket(bucketEpisodes).Put([]byte(episodeKey(f[1], f[2], f[3])), []byte(f[0]))
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("Imported %d episodes from %v", count, imdbEpisodesFile)
+
+	return nil
+}
+
+// writeDataset reads IMDB dataset and stores its rows into offline IMDB index in batches
+func writeDataset(db *bolt.DB, name string, fn func(tx *bolt.Tx, f []string) error) error {
+	var tx *bolt.Tx
+	rows := 0
+
+	err := readDataset(name, func(f []string) error {
+		var err error
+		if tx == nil {
+			tx, err = db.Begin(true)
+			if err != nil {
+				return err
+			}
+			for _, b := range [][]byte{bucketTitles, bucketNames, bucketEpisodes} {
+				if _, err = tx.CreateBucketIfNotExists(b); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err = fn(tx, f); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%offlineBatchSize == 0 {
+			err = tx.Commit()
+			tx = nil
+		}
+		return err
+	})
+
+	if tx != nil {
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+
+	return err
+}
+
+// readDataset reads gzipped IMDB TSV dataset skipping its header and calls fn for each row
+func readDataset(name string, fn func(f []string) error) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("unable to open IMDB dataset (download from %v): %v", imdbDatasetsURL, err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("unable to read IMDB dataset %v: %v", name, err)
+	}
+	defer gz.Close()
+
+	return scanDataset(gz, fn)
+}
+
+// scanDataset splits TSV rows into fields; IMDB datasets do not use quoting, so encoding/csv is not usable here
+func scanDataset(r io.Reader, fn func(f []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), offlineLineSize)
+
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		if err := fn(strings.Split(scanner.Text(), "\t")); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// normalizeTitle lowercases title and collapses everything other than letters and digits into single spaces
+func normalizeTitle(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// episodeKey returns offline IMDB index key for TV series episode
+func episodeKey(seriesID, season, episode string) string {
+	return seriesID + "\x00" + season + "\x00" + episode
+}
+
+// getOfflineTitle returns a single title from offline IMDB index by its IMDB Id
+func getOfflineTitle(tx *bolt.Tx, id string) (offlineTitle, bool) {
+	v := tx.Bucket(bucketTitles).Get([]byte(id))
+	if v == nil {
+		return offlineTitle{}, false
+	}
+
+	f := strings.Split(string(v), "\t")
+	if len(f) < 5 {
+		return offlineTitle{}, false
+	}
+
+	t := offlineTitle{id: id, kind: f[0], title: f[1], rating: "N/A"}
+	t.year, _ = strconv.Atoi(f[2])
+	if f[3] != imdbNullValue {
+		t.rating = f[3]
+	}
+	t.numVotes, _ = strconv.Atoi(f[4])
+
+	return t, true
+}
+
+// findOfflineTitle finds the best matching title of given IMDB title types: titles within a year of requested year
+// are preferred (actual release date and RIP date can have a 1-year offset), followed by the number of votes
+func findOfflineTitle(tx *bolt.Tx, mediaTitle string, mediaYear int, kinds ...string) (offlineTitle, bool) {
+	var best offlineTitle
+	found, bestYearMatch := false, false
+
+	prefix := []byte(normalizeTitle(mediaTitle) + "\x00")
+	c := tx.Bucket(bucketNames).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = c.Next() {
+		t, ok := getOfflineTitle(tx, string(k[len(prefix):]))
+		if !ok || !isOfflineKind(t.kind, kinds) {
+			continue
+		}
+
+		yearMatch := mediaYear != 0 && absInt(t.year-mediaYear) < 2
+		if !found || (yearMatch && !bestYearMatch) || (yearMatch == bestYearMatch && t.numVotes > best.numVotes) {
+			best, found, bestYearMatch = t, true, yearMatch
+		}
+	}
+
+	return best, found
+}
+
+// isOfflineKind checks if IMDB title type is one of given types
+func isOfflineKind(kind string, kinds []string) bool {
+	for _, v := range kinds {
+		if v == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveOffline resolves Movie or TV series episode from offline IMDB index, directly by IMDB Id if it is already
+// known (ie. from the filename) or by title otherwise
+func resolveOffline(m *mediaInfo) error {
+	if offlineIndex == nil {
+		return fmt.Errorf("offline IMDB index is not open")
+	}
+
+	return offlineIndex.View(func(tx *bolt.Tx) error {
+		var known offlineTitle
+		var ok bool
+		if m.imdbID != "" {
+			if known, ok = getOfflineTitle(tx, m.imdbID); !ok {
+				log.Debugf("IMDB Id %v not found in offline IMDB index, will retry with title", m.imdbID)
+			}
+		}
+
+		if !m.isTv {
+			t := known
+			m.byImdbID = ok
+			if !ok {
+				t, ok = findOfflineTitle(tx, m.title, m.year, "movie", "tvMovie", "video", "tvSpecial", "short")
+				if !ok {
+					return fmt.Errorf("movie not found in offline IMDB index")
+				}
+			}
+
+			setOfflineResult(m, t)
+			return nil
+		}
+
+		// Known IMDB Id could belong to either TV series or to the episode itself
+		if ok && known.kind == "tvEpisode" {
+			m.byImdbID = true
+			setOfflineResult(m, known)
+			return nil
+		}
+		series := known
+		byImdbID := ok && isOfflineKind(series.kind, []string{"tvSeries", "tvMiniSeries"})
+		if !byImdbID {
+			series, ok = findOfflineTitle(tx, m.title, m.year, "tvSeries", "tvMiniSeries")
+			if !ok {
+				return fmt.Errorf("TV series not found in offline IMDB index")
+			}
+		}
+
+		// IMDB datasets do not have episode air dates
+		if m.season == 0 && m.airDate != "" {
+			return fmt.Errorf("episode aired on %v of %v cannot be looked up in offline IMDB index", m.airDate,
+				series.id)
+		}
+
+		var id []byte
+		if m.absolute && m.season == 0 {
+			id = getOfflineAbsoluteEpisode(tx, series.id, m.episode)
+		} else {
+			id = tx.Bucket(bucketEpisodes).Get([]byte(episodeKey(series.id, strconv.Itoa(m.season),
+				strconv.Itoa(m.episode))))
+		}
+		if id == nil {
+			return fmt.Errorf("episode S%02dE%02d of %v not found in offline IMDB index", m.season, m.episode,
+				series.id)
+		}
+
+		t, ok := getOfflineTitle(tx, string(id))
+		if !ok {
+			return fmt.Errorf("episode %s not found in offline IMDB index", id)
+		}
+
+		m.byImdbID = byImdbID
+		m.seriesTitle = series.title
+		setOfflineResult(m, t)
+		return nil
+	})
+}
+
+// getOfflineAbsoluteEpisode returns IMDB Id of TV series episode by absolute episode number, counting episodes of
+// all regular seasons in order; specials (season 0) are not counted
+func getOfflineAbsoluteEpisode(tx *bolt.Tx, seriesID string, episode int) []byte {
+	type offlineEpisode struct {
+		season, episode int
+		id              []byte
+	}
+
+	var eps []offlineEpisode
+	prefix := []byte(seriesID + "\x00")
+	c := tx.Bucket(bucketEpisodes).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		f := strings.Split(string(k), "\x00")
+		if len(f) != 3 {
+			continue
+		}
+		s, err1 := strconv.Atoi(f[1])
+		e, err2 := strconv.Atoi(f[2])
+		if err1 != nil || err2 != nil || s == 0 {
+			continue
+		}
+		eps = append(eps, offlineEpisode{season: s, episode: e, id: append([]byte{}, v...)})
+	}
+
+	if episode < 1 || episode > len(eps) {
+		return nil
+	}
+
+	sort.Slice(eps, func(i, j int) bool {
+		if eps[i].season != eps[j].season {
+			return eps[i].season < eps[j].season
+		}
+		return eps[i].episode < eps[j].episode
+	})
+	return eps[episode-1].id
+}
+
+// setOfflineResult stores media identity and IMDB rating from offline IMDB index
+func setOfflineResult(m *mediaInfo, t offlineTitle) {
+	m.resolvedTitle = t.title
+	m.resolvedYear = zString(t.year)
+	m.imdbID = t.id
+	m.known[providerImdb] = t.rating
+}
+
+// getOfflineRating returns IMDB rating by IMDB Id from offline IMDB index
+func getOfflineRating(imdbID string) (string, error) {
+	if offlineIndex == nil {
+		return "", fmt.Errorf("offline IMDB index is not open")
+	}
+
+	rating := "N/A"
+	err := offlineIndex.View(func(tx *bolt.Tx) error {
+		if t, ok := getOfflineTitle(tx, imdbID); ok {
+			rating = t.rating
+		}
+		return nil
+	})
+
+	return rating, err
+}
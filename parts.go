@@ -0,0 +1,122 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DVD and Blu-ray disc structure folders, scored as a single title named after their parent folder
+var discDirs = map[string]bool{"video_ts": true, "bdmv": true}
+
+// DVD video object files stored directly in title folder, without VIDEO_TS folder
+var discFileRegexp = regexp.MustCompile(`(?i)^(video_ts|vts_[0-9]+_[0-9]+)\.vob$`)
+
+// Multi-part media files (ie. "Movie (2010) cd1.avi" or "Movie.part2.mkv") and folders (ie. "Movie (2010)/CD1")
+var partFileRegexp = regexp.MustCompile(`(?i)^(.*?)[ _.-]*[\[(]?(cd|dvd|disc|disk|part|pt)[ _.-]*[0-9]+[\])]?$`)
+var partDirRegexp = regexp.MustCompile(`(?i)^(cd|dvd|disc|disk|part|pt)[ _.-]*[0-9]+$`)
+
+// TV series episodes from box set "Disc N" folders are not parts of a single title
+var partEpisodeRegexp = regexp.MustCompile(`(?i)S\d{1,3}[ ._]?E\d{1,4}|\b\d{1,2}x\d{2,3}\b`)
+
+// isDiscDir checks if path is DVD or Blu-ray disc structure folder
+func isDiscDir(fullPath string) bool {
+	return discDirs[strings.ToLower(filepath.Base(fullPath))]
+}
+
+// getTitleDir returns the folder whose name is the identity of disc structures and media split into cdN/partN
+// subfolders or into bare cdN/partN files
+func getTitleDir(fullPath string) (string, bool) {
+	dir := filepath.Dir(fullPath)
+	name := filepath.Base(fullPath)
+
+	switch {
+	case isDiscDir(fullPath):
+		return dir, true
+	case discFileRegexp.MatchString(name):
+		if isDiscDir(dir) {
+			return filepath.Dir(dir), true
+		}
+		return dir, true
+	case partDirRegexp.MatchString(filepath.Base(dir)) && !partEpisodeRegexp.MatchString(name):
+		return filepath.Dir(dir), true
+	}
+
+	if m := partFileRegexp.FindStringSubmatch(strings.TrimSuffix(name, filepath.Ext(name))); m != nil && m[1] == "" {
+		return dir, true
+	}
+	return "", false
+}
+
+// getPartName returns media file name with part number stripped if there are other parts of the same media in the
+// same folder, as a single file ending with "Part 2" is rather a title on its own
+func getPartName(fullPath string) (string, bool) {
+	dir := filepath.Dir(fullPath)
+	name := filepath.Base(fullPath)
+	ext := filepath.Ext(name)
+
+	m := partFileRegexp.FindStringSubmatch(strings.TrimSuffix(name, ext))
+	if m == nil {
+		return "", false
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, f := range files {
+		if f.Name() == name || filepath.Ext(f.Name()) != ext {
+			continue
+		}
+		if o := partFileRegexp.FindStringSubmatch(strings.TrimSuffix(f.Name(), ext)); o != nil && o[1] == m[1] {
+			return m[1] + ext, true
+		}
+	}
+	return "", false
+}
+
+// getPartKey returns the key shared by all parts of multi-part media and disc structures, so that they are scored
+// only once
+func getPartKey(fullPath string) (string, bool) {
+	if dir, ok := getTitleDir(fullPath); ok {
+		return dir, true
+	}
+	if name, ok := getPartName(fullPath); ok {
+		return filepath.Join(filepath.Dir(fullPath), name), true
+	}
+	return "", false
+}
+
+// getMediaName returns the name media identity is parsed from: title folder name for disc structures and multi-part
+// media in subfolders, file name without part number for multi-part files, or just the file name
+func getMediaName(fullPath string) string {
+	if dir, ok := getTitleDir(fullPath); ok {
+		return filepath.Base(dir)
+	}
+	if name, ok := getPartName(fullPath); ok {
+		return name
+	}
+	return filepath.Base(fullPath)
+}
@@ -0,0 +1,279 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const providerImdb = "imdb"
+const providerRt = "rt"          // RottenTomatoes Tomatometer
+const providerRtAudience = "rta" // RottenTomatoes Audience Score
+const providerMc = "mc"          // Metacritic Metascore
+const providerMcUser = "mcu"     // Metacritic User Score
+
+var errNoResolver = errors.New("provider is not able to resolve media identity")
+
+// RatingProvider resolves media identity and fetches media rating from a single rating source
+type RatingProvider interface {
+	// Name returns short provider name used for enabling providers, sorting, filtering and weights
+	Name() string
+	// Header returns table column header for provider ratings
+	Header() string
+	// Scale returns maximum provider rating, used for normalization
+	Scale() float64
+	// Resolve resolves media identity (canonical title, year, IMDB Id etc.); providers which are not able to resolve
+	// media identity at all return errNoResolver
+	Resolve(m *mediaInfo) error
+	// Rating returns provider rating for already resolved media
+	Rating(m *mediaInfo) (string, error)
+}
+
+// mediaInfo holds media information parsed from the filename and media identity resolved by rating providers
+type mediaInfo struct {
+	title   string // media title as parsed from the filename
+	year    int    // optional media year
+	season  int    // TV series season
+	episode int    // TV series episode
+	isTv    bool
+	// TV series episodes without season number: daily shows are looked up by air date and anime by absolute episode
+	// number
+	absolute     bool // episode is an absolute episode number
+	multiEpisode bool // one of several episodes stored in a single file
+
+	resolvedTitle string            // canonical media title; for TV series it is the episode title
+	seriesTitle   string            // canonical TV series title, if known
+	resolvedYear  string            // canonical media year
+	imdbID        string            // IMDB Id, ie. "tt0111161"
+	airDate       string            // TV series episode air date, ie. "2008-01-20"; also used to look up daily shows
+	tomatoURL     string            // RottenTomatoes media page URL
+	fallback      bool              // media has been resolved only through a fallback search or provider
+	byImdbID      bool              // media has been resolved by IMDB Id known beforehand (filename, folder or .nfo)
+	known         map[string]string // ratings already obtained during identity resolution, keyed by provider name
+	sources       map[string]string // rating sources when they differ between lookups, keyed by provider name
+}
+
+// configuredProvider is implemented by rating providers which require configuration such as API keys
+type configuredProvider interface {
+	Configured() bool
+}
+
+// tvOnlyProvider is implemented by rating providers which rate TV series episodes only
+type tvOnlyProvider interface {
+	TvOnly() bool
+}
+
+// ratingProviders holds all available rating providers in their default order
+var ratingProviders = []RatingProvider{&imdbProvider{}, &rtProvider{}, &rtAudienceProvider{}, &mcProvider{},
+	&mcUserProvider{}, &tmdbProvider{}, &tvmazeProvider{}}
+
+// enabledProviders holds rating providers enabled from the command line, in requested order
+var enabledProviders = ratingProviders
+
+// newMediaInfo initializes media information from values parsed from the filename
+func newMediaInfo(mediaTitle string, mediaYear, mediaSeason, mediaEpisode int) *mediaInfo {
+	return &mediaInfo{title: mediaTitle, year: mediaYear, season: mediaSeason, episode: mediaEpisode,
+		isTv: mediaSeason > 0 && mediaEpisode > 0, known: make(map[string]string),
+		sources: make(map[string]string)}
+}
+
+// providerNames returns names of all available rating providers
+func providerNames() []string {
+	names := make([]string, len(ratingProviders))
+	for i, v := range ratingProviders {
+		names[i] = v.Name()
+	}
+	return names
+}
+
+// getProvider returns rating provider by its name
+func getProvider(name string) (RatingProvider, bool) {
+	for _, v := range ratingProviders {
+		if v.Name() == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// isProviderConfigured checks if rating provider has all required configuration; in offline mode only providers
+// able to work without network access are considered configured
+func isProviderConfigured(p RatingProvider) bool {
+	if o, ok := p.(offlineProvider); offlineMode && (!ok || !o.Offline()) {
+		return false
+	}
+	if c, ok := p.(configuredProvider); ok {
+		return c.Configured()
+	}
+	return true
+}
+
+// isProviderApplicable checks if rating provider rates given media type, ie. TV only providers do not rate movies
+func isProviderApplicable(p RatingProvider, isTv bool) bool {
+	if t, ok := p.(tvOnlyProvider); ok && t.TvOnly() {
+		return isTv
+	}
+	return true
+}
+
+// enableProviders enables rating providers by names in a given order; empty list enables all configured providers
+func enableProviders(names []string) error {
+	if len(names) == 0 {
+		enabledProviders = nil
+		for _, p := range ratingProviders {
+			if isProviderConfigured(p) {
+				enabledProviders = append(enabledProviders, p)
+			}
+		}
+		return nil
+	}
+
+	providers, err := getProviders(names)
+	if err != nil {
+		return err
+	}
+	for _, p := range providers {
+		if !isProviderConfigured(p) && offlineMode {
+			return fmt.Errorf("rating provider %v is not available in offline mode", p.Name())
+		}
+		if !isProviderConfigured(p) {
+			return fmt.Errorf("rating provider %v is not configured (missing API key?)", p.Name())
+		}
+	}
+
+	enabledProviders = providers
+	return nil
+}
+
+// enableCacheProviders enables rating providers by names in a given order for cache maintenance, regardless of
+// their configuration as cached ratings are available without API keys; empty list enables all providers
+func enableCacheProviders(names []string) error {
+	if len(names) == 0 {
+		enabledProviders = ratingProviders
+		return nil
+	}
+
+	providers, err := getProviders(names)
+	if err != nil {
+		return err
+	}
+
+	enabledProviders = providers
+	return nil
+}
+
+// getProviders returns rating providers by names in a given order, skipping duplicates
+func getProviders(names []string) ([]RatingProvider, error) {
+	var providers []RatingProvider
+	seen := make(map[string]bool)
+	for _, v := range names {
+		name := strings.ToLower(strings.TrimSpace(v))
+		p, ok := getProvider(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown rating provider: %v", v)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		providers = append(providers, p)
+	}
+
+	return providers, nil
+}
+
+// isProviderEnabled checks if rating provider is enabled
+func isProviderEnabled(name string) bool {
+	for _, p := range enabledProviders {
+		if p.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMedia resolves media identity by trying enabled rating providers in order until one succeeds; disabled but
+// configured providers are tried last so that media can be resolved even when all enabled providers only supply
+// ratings
+func resolveMedia(m *mediaInfo) error {
+	providers := append([]RatingProvider{}, enabledProviders...)
+	for _, p := range ratingProviders {
+		if !isProviderEnabled(p.Name()) && isProviderConfigured(p) {
+			providers = append(providers, p)
+		}
+	}
+
+	// First error is kept as it comes from the preferred provider
+	var err error
+	for _, p := range providers {
+		perr := p.Resolve(m)
+		if perr == errNoResolver {
+			continue
+		}
+		if perr != nil {
+			log.Debugf("Rating provider %v could not resolve media %q: %v", p.Name(), m.title, perr)
+			if err == nil {
+				err = perr
+			}
+			continue
+		}
+
+		// Media resolved only after the preferred provider has failed
+		if err != nil {
+			m.fallback = true
+		}
+		return nil
+	}
+
+	if err == nil {
+		err = fmt.Errorf("none of rating providers is able to resolve media identity")
+	}
+	return err
+}
+
+// parseProviderValues parses a list of provider=value pairs (ie. "imdb=7.5") into a map keyed by provider name
+func parseProviderValues(list []string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	for _, v := range list {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid value, expected provider=value: %v", v)
+		}
+
+		name := strings.ToLower(strings.TrimSpace(kv[0]))
+		if _, ok := getProvider(name); !ok {
+			return nil, fmt.Errorf("unknown rating provider: %v", kv[0])
+		}
+
+		f, ok := parseRating(kv[1])
+		if !ok {
+			return nil, fmt.Errorf("invalid value for %v: %v", name, kv[1])
+		}
+		values[name] = f
+	}
+
+	return values, nil
+}
@@ -22,128 +22,159 @@
 package main
 
 import (
-	"github.com/dkorunic/gomdb"
+	"bytes"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 )
 
-var omdbKey string // OMDb API key: https://www.omdbapi.com/
+// getRatings resolves media parsed from a given file (title with optional year, season, episode, air date and IMDB Id
+// information) and gathers ratings from all enabled rating providers; fully populated information structure is sent
+// to rendering channel
+func getRatings(fullPath string, m *mediaInfo, channel chan<- renderTable) error {
+	mediaTitle := m.title
 
-// getRatings gathers OMDb, IMDB, RottenTomatoes and MediaCritic information about given media title with optional year,
-// season and episode information and fully populated information structure is sent to rendering channel
-func getRatings(baseName, mediaTitle string, mediaYear, mediaSeason, mediaEpisode int,
-	channel chan<- renderTable) error {
 	// Initial cache lookup with filename hash: we are not sure at this point if this is TV series of Movie, so lookup
-	// in both cache tables
+	// in both cache tables; each episode of multi-episode file has its own filename hash
 	var cacheEntry CacheEntry
+	var stale *CacheEntry
+	baseName := getMediaName(fullPath)
 	baseNameHash := getCacheKey(baseName)
+	if m.multiEpisode {
+		baseNameHash = getCacheKey(baseName, zString(m.episode))
+	}
 	err := getCacheOne(cacheTv, "BaseNameHash", baseNameHash, &cacheEntry)
 	if err != nil {
 		log.Debugf("TV series file %v (decoded: %v/%v/%v/%v) not found in cache: %v", baseName, mediaTitle,
-			mediaYear, mediaSeason, mediaEpisode, err)
-	} else {
-		channel <- renderTable{isCached: true, data: cacheEntry}
+			m.year, m.season, m.episode, err)
+	} else if isCacheFresh(cacheEntry) {
+		channel <- renderTable{isCached: true, data: cacheEntry, path: fullPath}
 		return nil
-	}
-	err = getCacheOne(cacheMovie, "BaseNameHash", baseNameHash, &cacheEntry)
-	if err != nil {
-		log.Debugf("Movie file %v (decoded: %v/%v) not found in cache: %v", baseName, mediaTitle, mediaYear,
-			err)
 	} else {
-		channel <- renderTable{isCached: true, data: cacheEntry}
-		return nil
+		log.Debugf("TV series file %v found in cache, but it is stale and will be refreshed", baseName)
+		entry := cacheEntry
+		stale = &entry
 	}
-
-	// Prepare OMDb query
-	api := gomdb.Init(omdbKey)
-	query := &gomdb.QueryData{Title: mediaTitle, Year: zString(mediaYear)}
-
-	isTv := false
-	if mediaSeason > 0 && mediaEpisode > 0 {
-		isTv = true
-
-		query.Season = zString(mediaSeason)
-		query.Episode = zString(mediaEpisode)
-		query.SearchType = gomdb.EpisodeSearch
-
-	} else {
-		query.SearchType = gomdb.MovieSearch
-	}
-
-	// OMDb query by title (type "t")
-	res, err := api.MovieByTitle(query)
-	if err != nil {
-		log.Debugf("Could not find media %q in OMDb, will retry with IMDB lookup: %v", mediaTitle, err)
-
-		// IMDB query by title
-		imdbID, err := getImdbId(mediaTitle, mediaYear)
+	if stale == nil {
+		err = getCacheOne(cacheMovie, "BaseNameHash", baseNameHash, &cacheEntry)
 		if err != nil {
-			log.Debugf("Could not query IMDB with media %q: %v", mediaTitle, err)
-			return err
+			log.Debugf("Movie file %v (decoded: %v/%v) not found in cache: %v", baseName, mediaTitle, m.year, err)
+		} else if isCacheFresh(cacheEntry) {
+			channel <- renderTable{isCached: true, data: cacheEntry, path: fullPath}
+			return nil
+		} else {
+			log.Debugf("Movie file %v found in cache, but it is stale and will be refreshed", baseName)
+			entry := cacheEntry
+			stale = &entry
 		}
+	}
 
-		// do another OMDb query by IMDB Id (type "i")
-		query.ImdbId = imdbID
-		res, err = api.MovieByImdbID(query)
-		if err != nil {
-			log.Debugf("Could not query IMDB with for media %q and IMDB ID %v: %v", mediaTitle, query.ImdbId,
-				err)
-			return err
+	// Stale cache entry is still better than nothing when refreshing fails
+	fallback := func(err error) error {
+		if stale != nil {
+			log.Debugf("Unable to refresh %v, using stale cache entry: %v", baseName, err)
+			channel <- renderTable{isCached: true, data: *stale, path: fullPath}
+			return nil
 		}
+		return err
 	}
 
+	// Resolve media identity: canonical title, year and optional episode title
+	if err := resolveMedia(m); err != nil {
+		return fallback(err)
+	}
+	season, episode := zString(m.season), zString(m.episode)
+	mediaTitle = getCacheTitle(m)
+
 	// We have title, year, season and episode details and attempt to lookup them in cache
-	if isTv {
+	if m.isTv {
 		// hash(Title, Year, Season, Episode)
-		keyId := getCacheKey(mediaTitle, res.Year, query.Season, query.Episode)
+		keyId := getCacheKey(mediaTitle, m.resolvedYear, season, episode)
 		err := getCacheOne(cacheTv, "Id", keyId, &cacheEntry)
 		if err != nil {
-			log.Debugf("TV series %v/%v/%v/%v (internal: %v) not found in cache: %v", mediaTitle, query.Year,
-				query.Season, query.Episode, keyId, err)
-		} else {
-			channel <- renderTable{isCached: true, data: cacheEntry}
+			log.Debugf("TV series %v/%v/%v/%v (internal: %v) not found in cache: %v", mediaTitle, m.resolvedYear,
+				season, episode, keyId, err)
+		} else if isCacheFresh(cacheEntry) {
+			channel <- renderTable{isCached: true, data: cacheEntry, path: fullPath}
 			return nil
 		}
 	} else {
 		// hash(Title, Year)
-		keyId := getCacheKey(mediaTitle, query.Year)
+		keyId := getCacheKey(mediaTitle, m.resolvedYear)
 		err := getCacheOne(cacheMovie, "Id", keyId, &cacheEntry)
 		if err != nil {
-			log.Debugf("Movie %v/%v (internal: %v) not found in cache: %v", mediaTitle, mediaYear, keyId, err)
-		} else {
-			channel <- renderTable{isCached: true, data: cacheEntry}
+			log.Debugf("Movie %v/%v (internal: %v) not found in cache: %v", mediaTitle, m.resolvedYear, keyId,
+				err)
+		} else if isCacheFresh(cacheEntry) {
+			channel <- renderTable{isCached: true, data: cacheEntry, path: fullPath}
 			return nil
 		}
 	}
 
-	// RottenTomatoes scraping: only if OMDb doesn't have RT score
-	if res.TomatoRating == "N/A" {
-		rt, err := getRtScore(mediaTitle, res.Title, mediaSeason, res.TomatoURL, isTv)
-		if err != nil {
-			log.Debugf("Could not get RottenTomatoes rating for media %q: %v", mediaTitle, err)
-		} else {
-			res.TomatoRating = rt
+	// Get ratings from all enabled providers; when refreshing the same media, ratings which could not be fetched are
+	// kept from stale cache entry
+	ratings := make(map[string]string)
+	for _, p := range enabledProviders {
+		rating, err := p.Rating(m)
+		if err != nil && stale != nil && stale.ImdbID == m.imdbID {
+			if v, ok := stale.Ratings[p.Name()]; ok {
+				log.Debugf("Could not refresh %v rating for media %q, keeping stale rating: %v", p.Name(),
+					mediaTitle, err)
+				ratings[p.Name()] = v
+				if src, ok := stale.Sources[p.Name()]; ok {
+					m.sources[p.Name()] = src
+				} else {
+					delete(m.sources, p.Name())
+				}
+				continue
+			}
 		}
-	}
-
-	// Metacritic scraping
-	metaCriticRating, err := getMcScore(mediaTitle, res.Title, mediaYear, mediaSeason, mediaEpisode, isTv)
-	if err != nil {
-		log.Debugf("Could not get Metacritic rating for media %q: %v", mediaTitle, err)
-		metaCriticRating = "N/A"
+		if err != nil || rating == "" {
+			log.Debugf("Could not get %v rating for media %q: %v", p.Name(), mediaTitle, err)
+			rating = "N/A"
+		}
+		ratings[p.Name()] = rating
 	}
 
 	// We now have all data, send it to rendering and set cache flag to yes
-	if isTv {
-		cacheEntry = CacheEntry{Title: mediaTitle, Year: res.Year, EpisodeTitle: res.Title,
-			Season: query.Season, EpisodeNr: query.Episode, ImdbRating: res.ImdbRating,
-			RtRating: res.TomatoRating, McRating: metaCriticRating, BaseNameHash: getCacheKey(baseName),
-			IsTv: isTv, Id: getCacheKey(mediaTitle, res.Year, query.Season, query.Episode)}
+	if m.isTv {
+		cacheEntry = CacheEntry{Title: mediaTitle, Year: m.resolvedYear, EpisodeTitle: m.resolvedTitle,
+			Season: season, EpisodeNr: episode, AirDate: m.airDate, Ratings: ratings, Sources: m.sources,
+			ImdbID: m.imdbID, Confidence: matchConfidence(m), BaseNameHash: baseNameHash, IsTv: m.isTv,
+			Id: getCacheKey(mediaTitle, m.resolvedYear, season, episode), FetchedAt: time.Now()}
 	} else {
-		cacheEntry = CacheEntry{Title: mediaTitle, Year: res.Year, ImdbRating: res.ImdbRating,
-			RtRating: res.TomatoRating, McRating: metaCriticRating, BaseNameHash: getCacheKey(baseName),
-			IsTv: isTv, Id: getCacheKey(mediaTitle, res.Year)}
+		cacheEntry = CacheEntry{Title: mediaTitle, Year: m.resolvedYear, Ratings: ratings, Sources: m.sources,
+			ImdbID: m.imdbID, Confidence: matchConfidence(m), BaseNameHash: baseNameHash, IsTv: m.isTv,
+			Id: getCacheKey(mediaTitle, m.resolvedYear), FetchedAt: time.Now()}
+	}
+
+	// Remove stale entry if refreshed media has been resolved to a different identity
+	if stale != nil && !bytes.Equal(stale.Id, cacheEntry.Id) {
+		if stale.IsTv {
+			_ = deleteCache(cacheTv, stale)
+		} else {
+			_ = deleteCache(cacheMovie, stale)
+		}
 	}
-	channel <- renderTable{isCached: false, data: cacheEntry}
+	channel <- renderTable{isCached: false, data: cacheEntry, path: fullPath}
 
 	return nil
 }
+
+// getCacheTitle returns media title used for cache entries and their Id: media resolved by IMDB Id known beforehand
+// uses its canonical title, as the filename may carry little more than the IMDB Id tag (ie. "tt0111161.mkv" or
+// "Movie {imdb-tt0111161}/movie.mkv") and titles parsed from such files would collide in cache
+func getCacheTitle(m *mediaInfo) string {
+	title := m.resolvedTitle
+	if m.isTv {
+		title = m.seriesTitle
+	}
+
+	switch {
+	case title != "" && (m.byImdbID || m.title == ""):
+		return title
+	case m.title == "" || (m.byImdbID && !m.isTv):
+		return m.imdbID
+	}
+	return m.title
+}
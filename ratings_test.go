@@ -0,0 +1,49 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import "testing"
+
+func TestGetCacheTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		m    mediaInfo
+		want string
+	}{
+		{"parsed title", mediaInfo{title: "Shawshank", resolvedTitle: "The Shawshank Redemption"}, "Shawshank"},
+		{"IMDB Id tag only", mediaInfo{byImdbID: true, imdbID: "tt0111161",
+			resolvedTitle: "The Shawshank Redemption"}, "The Shawshank Redemption"},
+		{"IMDB Id tagged folder", mediaInfo{title: "movie", byImdbID: true, imdbID: "tt0111161",
+			resolvedTitle: "The Shawshank Redemption"}, "The Shawshank Redemption"},
+		{"unknown canonical title", mediaInfo{title: "movie", byImdbID: true, imdbID: "tt0111161"}, "tt0111161"},
+		{"TV series", mediaInfo{title: "show", isTv: true, byImdbID: true, imdbID: "tt0903747",
+			resolvedTitle: "Pilot", seriesTitle: "Breaking Bad"}, "Breaking Bad"},
+		{"TV series without series title", mediaInfo{title: "show", isTv: true, byImdbID: true,
+			imdbID: "tt0903747", resolvedTitle: "Pilot"}, "show"},
+	}
+
+	for _, tt := range tests {
+		if got := getCacheTitle(&tt.m); got != tt.want {
+			t.Errorf("%v: getCacheTitle() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const outputTable = "table"       // console tables (default)
+const outputJSON = "json"         // single JSON document
+const outputNDJSON = "ndjson"     // one JSON object per line, streamed
+const outputCSV = "csv"           // comma separated values
+const outputTSV = "tsv"           // tab separated values
+const outputMarkdown = "markdown" // GitHub-flavored Markdown tables
+const outputHTML = "html"         // self-contained HTML report
+
+const matchHeader = "Match" // match confidence column header
+const filesHeader = "Files" // file count column header
+
+var tableUnmatchedHeader = []string{"Title", "Year", "Reason"}
+var outputFormats = []string{outputTable, outputJSON, outputNDJSON, outputCSV, outputTSV, outputMarkdown, outputHTML}
+
+// mediaRenderer receives scored media one entry at a time and produces final output once all media has been processed
+type mediaRenderer interface {
+	Append(v renderTable) error
+	Render() error
+}
+
+// outputEntry is a flattened, serializable view of a single rendered media entry
+type outputEntry struct {
+	Title        string            `json:"title"`
+	Year         string            `json:"year"`
+	EpisodeTitle string            `json:"episode_title,omitempty"`
+	Season       string            `json:"season,omitempty"`
+	Episode      string            `json:"episode,omitempty"`
+	AirDate      string            `json:"air_date,omitempty"`
+	ImdbID       string            `json:"imdb_id,omitempty"`
+	Confidence   string            `json:"confidence,omitempty"`
+	Reason       string            `json:"reason,omitempty"` // why media could not be matched
+	Ratings      map[string]string `json:"ratings"`
+	Sources      map[string]string `json:"rating_sources,omitempty"`
+	Combined     string            `json:"combined"`
+	IsTv         bool              `json:"is_tv"`
+	IsCached     bool              `json:"cached"`
+	Path         string            `json:"path"`
+	Files        int               `json:"files"`
+	Paths        []string          `json:"paths,omitempty"` // all file paths of media found in several files
+}
+
+// newRenderer returns a renderer for a given output format writing to w; splitPath is used only by renderers
+// capable of writing Movie and TV media into separate files
+func newRenderer(format string, w io.Writer, splitPath string) (mediaRenderer, error) {
+	if splitPath != "" && format != outputCSV && format != outputTSV {
+		return nil, fmt.Errorf("separate Movie/TV files are not supported for output format: %v", format)
+	}
+
+	switch format {
+	case outputTable:
+		return newTableRenderer(w), nil
+	case outputJSON:
+		return newJSONRenderer(w), nil
+	case outputNDJSON:
+		return newNDJSONRenderer(w), nil
+	case outputCSV:
+		return newCSVRenderer(w, ',', splitPath), nil
+	case outputTSV:
+		return newCSVRenderer(w, '\t', splitPath), nil
+	case outputMarkdown:
+		return newMarkdownRenderer(w), nil
+	case outputHTML:
+		return newHTMLRenderer(w), nil
+	}
+
+	return nil, fmt.Errorf("unknown output format: %v", format)
+}
+
+// newOutputEntry converts renderTable into outputEntry with ratings from enabled rating providers; unmatched media has
+// no ratings
+func newOutputEntry(v renderTable) outputEntry {
+	if v.reason != "" {
+		return outputEntry{Title: v.data.Title, Year: v.data.Year, Season: v.data.Season, Episode: v.data.EpisodeNr,
+			Reason: v.reason, IsTv: v.data.IsTv, Path: v.path, Files: 1}
+	}
+
+	var paths []string
+	if len(v.duplicates) > 0 {
+		paths = append([]string{v.path}, v.duplicates...)
+	}
+
+	return outputEntry{Title: v.data.Title, Year: v.data.Year, EpisodeTitle: v.data.EpisodeTitle,
+		Season: v.data.Season, Episode: v.data.EpisodeNr, AirDate: v.data.AirDate, ImdbID: v.data.ImdbID,
+		Ratings: enabledRatings(v.data), Sources: enabledSources(v.data), Combined: formatScore(combinedScore(v.data)),
+		Confidence: v.data.Confidence, IsTv: v.data.IsTv, IsCached: v.isCached, Path: v.path,
+		Files: 1 + len(v.duplicates), Paths: paths}
+}
+
+// enabledRatings returns ratings of enabled rating providers only, with N/A for missing ones
+func enabledRatings(data CacheEntry) map[string]string {
+	ratings := make(map[string]string, len(enabledProviders))
+	for _, p := range enabledProviders {
+		ratings[p.Name()] = getRating(data, p.Name())
+	}
+	return ratings
+}
+
+// enabledSources returns rating sources of enabled rating providers which have them recorded
+func enabledSources(data CacheEntry) map[string]string {
+	var sources map[string]string
+	for _, p := range enabledProviders {
+		if v, ok := data.Sources[p.Name()]; ok && getRating(data, p.Name()) != "N/A" {
+			if sources == nil {
+				sources = make(map[string]string)
+			}
+			sources[p.Name()] = v
+		}
+	}
+	return sources
+}
+
+// labelledEntry returns a copy of outputEntry with ratings labelled by their source, ie. "85 (OMDb)", for
+// human-readable output formats
+func labelledEntry(e outputEntry) outputEntry {
+	if len(e.Sources) == 0 {
+		return e
+	}
+
+	ratings := make(map[string]string, len(e.Ratings))
+	for k, v := range e.Ratings {
+		if src, ok := e.Sources[k]; ok {
+			v = fmt.Sprintf("%v (%v)", v, src)
+		}
+		ratings[k] = v
+	}
+	e.Ratings = ratings
+	return e
+}
+
+// getRating returns a single rating from a cache entry, or N/A if missing
+func getRating(data CacheEntry, name string) string {
+	if v, ok := data.Ratings[name]; ok && v != "" {
+		return v
+	}
+	return "N/A"
+}
+
+// ratingHeaders returns table column headers of enabled rating providers followed by combined score header
+func ratingHeaders() []string {
+	var header []string
+	for _, p := range enabledProviders {
+		header = append(header, p.Header())
+	}
+	return append(header, "Combined")
+}
+
+// ratingCells returns ratings of enabled rating providers followed by combined score, matching ratingHeaders()
+func ratingCells(e outputEntry) []string {
+	var cells []string
+	for _, p := range enabledProviders {
+		cells = append(cells, e.Ratings[p.Name()])
+	}
+	return append(cells, e.Combined)
+}
+
+// matchCell returns match confidence of matched media, or reason of unmatched media
+func matchCell(e outputEntry) string {
+	switch {
+	case e.Reason != "":
+		return e.Reason
+	case e.Confidence != "":
+		return e.Confidence
+	}
+	return "N/A"
+}
+
+// pathCell returns all file paths of media, one per line
+func pathCell(e outputEntry) string {
+	if len(e.Paths) > 0 {
+		return strings.Join(e.Paths, "\n")
+	}
+	return e.Path
+}
+
+// tvRow returns outputEntry formatted as TV table row, matching tableTvHeader columns
+func tvRow(e outputEntry) []string {
+	return append(append([]string{e.Title, e.Year, e.EpisodeTitle, e.Season, e.Episode}, ratingCells(e)...),
+		matchCell(e), strconv.Itoa(e.Files))
+}
+
+// movieRow returns outputEntry formatted as Movie table row, matching tableMovieHeader columns
+func movieRow(e outputEntry) []string {
+	return append(append([]string{e.Title, e.Year}, ratingCells(e)...), matchCell(e), strconv.Itoa(e.Files))
+}
+
+// pathHeader returns table header with file paths column appended if requested
+func pathHeader(header []string, paths bool) []string {
+	if !paths {
+		return header
+	}
+	return append(append([]string{}, header...), csvPathHeader)
+}
+
+// pathRow returns table row ending with file paths column, with that column removed unless requested
+func pathRow(row []string, paths bool) []string {
+	if !paths {
+		return row[:len(row)-1]
+	}
+	return row
+}
+
+// unmatchedHeader returns Unmatched table header including file path column, which is always shown for unmatched
+// media as it might have no title at all
+func unmatchedHeader() []string {
+	return append(append([]string{}, tableUnmatchedHeader...), csvPathHeader)
+}
+
+// unmatchedRow returns outputEntry formatted as Unmatched table row, matching tableUnmatchedHeader columns
+func unmatchedRow(e outputEntry) []string {
+	return []string{e.Title, e.Year, e.Reason}
+}
@@ -27,11 +27,78 @@ import (
 )
 
 const rtBaseUrl = "https://www.rottentomatoes.com"
-const rtTvScoreSelector = ".superPageFontColor.meter-align"                                             // RT TV score
-const rtMovieScoreSelector = "span.mop-ratings-wrap__percentage.mop-ratings-wrap__percentage--audience" // RT Movie score
+const rtSource = "RottenTomatoes"                                                                             // rating source label for scraped scores
+const rtTvScoreSelector = ".superPageFontColor.meter-align"                                                   // RT TV Tomatometer
+const rtTvAudienceSelector = ".audience-score .superPageFontColor"                                            // RT TV Audience Score
+const rtMovieScoreSelector = "span.mop-ratings-wrap__percentage:not(.mop-ratings-wrap__percentage--audience)" // RT Movie Tomatometer
+const rtMovieAudienceSelector = "span.mop-ratings-wrap__percentage.mop-ratings-wrap__percentage--audience"    // RT Movie Audience Score
 
-// getRtScore gets RottenTomatoes score
-func getRtScore(mediaTitle, omdbTitle string, mediaSeason int, tomatoUrl string, isTv bool) (string, error) {
+// rtProvider fetches RottenTomatoes Tomatometer (critics score), either from OMDb or by scraping RottenTomatoes media
+// page
+type rtProvider struct{}
+
+// Name returns RottenTomatoes Tomatometer provider name
+func (p *rtProvider) Name() string { return providerRt }
+
+// Header returns RottenTomatoes Tomatometer table column header
+func (p *rtProvider) Header() string { return "RT Tomatometer" }
+
+// Scale returns maximum RottenTomatoes Tomatometer
+func (p *rtProvider) Scale() float64 { return 100 }
+
+// Resolve is not supported by RottenTomatoes provider
+func (p *rtProvider) Resolve(m *mediaInfo) error { return errNoResolver }
+
+// Rating returns RottenTomatoes Tomatometer from OMDb and scrapes RottenTomatoes only if OMDb doesn't have it
+func (p *rtProvider) Rating(m *mediaInfo) (string, error) {
+	return getRtRating(m, providerRt)
+}
+
+// rtAudienceProvider fetches RottenTomatoes Audience Score, either from OMDb or by scraping RottenTomatoes media page
+type rtAudienceProvider struct{}
+
+// Name returns RottenTomatoes Audience Score provider name
+func (p *rtAudienceProvider) Name() string { return providerRtAudience }
+
+// Header returns RottenTomatoes Audience Score table column header
+func (p *rtAudienceProvider) Header() string { return "RT Audience" }
+
+// Scale returns maximum RottenTomatoes Audience Score
+func (p *rtAudienceProvider) Scale() float64 { return 100 }
+
+// Resolve is not supported by RottenTomatoes provider
+func (p *rtAudienceProvider) Resolve(m *mediaInfo) error { return errNoResolver }
+
+// Rating returns RottenTomatoes Audience Score from OMDb and scrapes RottenTomatoes only if OMDb doesn't have it
+func (p *rtAudienceProvider) Rating(m *mediaInfo) (string, error) {
+	return getRtRating(m, providerRtAudience)
+}
+
+// getRtRating returns already known RottenTomatoes score, or scrapes RottenTomatoes media page once for both
+// Tomatometer and Audience Score
+func getRtRating(m *mediaInfo, name string) (string, error) {
+	if v, ok := m.known[name]; ok {
+		return v, nil
+	}
+
+	critics, audience, err := getRtScore(m.title, m.resolvedTitle, m.season, m.tomatoURL, m.isTv)
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range map[string]string{providerRt: critics, providerRtAudience: audience} {
+		if _, ok := m.known[k]; !ok {
+			m.known[k] = v
+			m.sources[k] = rtSource
+		}
+	}
+
+	return m.known[name], nil
+}
+
+// getRtScore gets RottenTomatoes Tomatometer and Audience Score
+func getRtScore(mediaTitle, omdbTitle string, mediaSeason int, tomatoUrl string, isTv bool) (string, string,
+	error) {
 	// Generate RT media URL if OMDb doesn't provide it
 	if tomatoUrl == "" || tomatoUrl == "N/A" {
 		if isTv {
@@ -43,27 +110,32 @@ func getRtScore(mediaTitle, omdbTitle string, mediaSeason int, tomatoUrl string,
 
 	res, doc, err := getMediaDoc(tomatoUrl, rtBaseUrl)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer res.Body.Close()
 
-	// Extract RT rating
-	var rating string
+	// Extract RT ratings
+	var critics, audience string
 	if isTv {
-		rating = doc.Find(rtTvScoreSelector).
-			First().Text()
+		critics = doc.Find(rtTvScoreSelector).First().Text()
+		audience = doc.Find(rtTvAudienceSelector).First().Text()
 	} else {
-		rating = doc.Find(rtMovieScoreSelector).
-			First().Text()
+		critics = doc.Find(rtMovieScoreSelector).First().Text()
+		audience = doc.Find(rtMovieAudienceSelector).First().Text()
 	}
-	// Cleanup newlines, get first number, cleanup percentage signs
+
+	return cleanRtScore(critics), cleanRtScore(audience), nil
+}
+
+// cleanRtScore cleans up newlines, gets first number and cleans up percentage signs
+func cleanRtScore(rating string) string {
 	rating = strings.Trim(strings.Trim(strings.Split(strings.TrimSpace(rating), " ")[0], "\n"), "%")
 
 	if rating == "" || !isInt(rating) {
-		return "N/A", nil
+		return "N/A"
 	}
 
-	return rating, nil
+	return rating
 }
 
 // getRtName creates a RT-compatible media title, encoding spaces with underscore and removing colons
@@ -0,0 +1,64 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"strconv"
+)
+
+// scoreWeights holds per-provider weights for combined score; providers without explicit weight have weight 1
+var scoreWeights = make(map[string]float64)
+
+// combinedScore returns a weighted average of enabled rating providers' ratings, normalized to 0-100 range; ratings
+// that are not available are ignored and remaining weights re-balanced accordingly; second return value is false if
+// no weighted rating is available at all
+func combinedScore(data CacheEntry) (float64, bool) {
+	var sum, weights float64
+
+	for _, p := range enabledProviders {
+		w, ok := scoreWeights[p.Name()]
+		if !ok {
+			w = 1
+		}
+		if w <= 0 {
+			continue
+		}
+		if f, ok := parseRating(data.Ratings[p.Name()]); ok {
+			sum += w * f / p.Scale()
+			weights += w
+		}
+	}
+
+	if weights == 0 {
+		return 0, false
+	}
+
+	return 100 * sum / weights, true
+}
+
+// formatScore formats combined score with a single decimal, or returns N/A if score is not available
+func formatScore(score float64, ok bool) string {
+	if !ok {
+		return "N/A"
+	}
+	return strconv.FormatFloat(score, 'f', 1, 64)
+}
@@ -0,0 +1,130 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// tableRenderer renders Movie, TV and unmatched media in separate console tables
+type tableRenderer struct {
+	w                                            io.Writer
+	tvTable, movieTable, unmatchedTable          *tablewriter.Table
+	tvTableCtr, movieTableCtr, unmatchedTableCtr int
+	tvRows, movieRows                            [][]string // Movie and TV rows with file paths as the last column
+	duplicates                                   bool       // some media has been found in several files
+}
+
+// newTableRenderer initializes Unmatched table header and style; Movie and TV tables are initialized when rendering as
+// their columns depend on media found in several files
+func newTableRenderer(w io.Writer) *tableRenderer {
+	return &tableRenderer{w: w, unmatchedTable: unmatchedTableInit(w)}
+}
+
+// Append reformats and pushes media entry to appropriate table
+func (t *tableRenderer) Append(v renderTable) error {
+	e := labelledEntry(newOutputEntry(v))
+	if e.Reason != "" {
+		t.unmatchedTable.Append(append(unmatchedRow(e), e.Path))
+		t.unmatchedTableCtr++
+	} else if e.IsTv {
+		t.tvRows = append(t.tvRows, append(tvRow(e), pathCell(e)))
+		t.tvTableCtr++
+	} else {
+		t.movieRows = append(t.movieRows, append(movieRow(e), pathCell(e)))
+		t.movieTableCtr++
+	}
+	t.duplicates = t.duplicates || e.Files > 1
+
+	return nil
+}
+
+// Render renders non-empty Movie and TV tables, with file paths column if any media has been found in several files
+func (t *tableRenderer) Render() error {
+	t.tvTable, t.movieTable = tvTableInit(t.w, t.duplicates), movieTableInit(t.w, t.duplicates)
+	for _, v := range t.tvRows {
+		t.tvTable.Append(pathRow(v, t.duplicates))
+	}
+	for _, v := range t.movieRows {
+		t.movieTable.Append(pathRow(v, t.duplicates))
+	}
+
+	// Render Movie table only if not empty
+	if t.movieTableCtr > 0 {
+		t.movieTable.Render()
+		if t.tvTableCtr > 0 {
+			fmt.Fprint(t.w, "\n")
+		}
+	}
+	// Similarly render TV table only if not empty
+	if t.tvTableCtr > 0 {
+		t.tvTable.Render()
+		if t.unmatchedTableCtr > 0 {
+			fmt.Fprint(t.w, "\n")
+		}
+	}
+	// Unmatched media goes last
+	if t.unmatchedTableCtr > 0 {
+		if t.movieTableCtr > 0 && t.tvTableCtr == 0 {
+			fmt.Fprint(t.w, "\n")
+		}
+		t.unmatchedTable.Render()
+	}
+
+	return nil
+}
+
+// tvTableInit initializes TV table with header, formatting style, separator and borders
+func tvTableInit(w io.Writer, paths bool) *tablewriter.Table {
+	tvTable := tablewriter.NewWriter(w)
+	tvTable.SetHeader(pathHeader(tableTvHeader, paths))
+	tvTable.SetCaption(true, "TV Series Ratings ----------^")
+	tvTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	tvTable.SetCenterSeparator("|")
+
+	return tvTable
+}
+
+// movieTableInit initializes Movie table with header, formatting style, separator and borders
+func movieTableInit(w io.Writer, paths bool) *tablewriter.Table {
+	movieTable := tablewriter.NewWriter(w)
+	movieTable.SetHeader(pathHeader(tableMovieHeader, paths))
+	movieTable.SetCaption(true, "Movie Ratings ----------^")
+	movieTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	movieTable.SetCenterSeparator("|")
+
+	return movieTable
+}
+
+// unmatchedTableInit initializes Unmatched table with header, formatting style, separator and borders
+func unmatchedTableInit(w io.Writer) *tablewriter.Table {
+	unmatchedTable := tablewriter.NewWriter(w)
+	unmatchedTable.SetHeader(unmatchedHeader())
+	unmatchedTable.SetCaption(true, "Unmatched ----------^")
+	unmatchedTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	unmatchedTable.SetCenterSeparator("|")
+
+	return unmatchedTable
+}
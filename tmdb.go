@@ -0,0 +1,257 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const providerTmdb = "tmdb"
+
+var tmdbKey string                               // TMDb API key: https://www.themoviedb.org/settings/api
+var tmdbBaseUrl = "https://api.themoviedb.org/3" // TMDb API endpoint
+
+// tmdbSearchResult is a single TMDb Movie or TV series search result
+type tmdbSearchResult struct {
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	Name         string `json:"name"`
+	ReleaseDate  string `json:"release_date"`
+	FirstAirDate string `json:"first_air_date"`
+}
+
+// tmdbSearchResponse is TMDb Movie or TV series search response
+type tmdbSearchResponse struct {
+	Results []tmdbSearchResult `json:"results"`
+}
+
+// tmdbMedia holds TMDb Movie or TV series episode details
+type tmdbMedia struct {
+	ID          int     `json:"id"`
+	ImdbID      string  `json:"imdb_id"`
+	Title       string  `json:"title"`
+	Name        string  `json:"name"`
+	ReleaseDate string  `json:"release_date"`
+	AirDate     string  `json:"air_date"`
+	VoteAverage float64 `json:"vote_average"`
+	VoteCount   int     `json:"vote_count"`
+}
+
+// tmdbFindResponse is TMDb response when looking up media by external (IMDB) Id
+type tmdbFindResponse struct {
+	MovieResults     []tmdbSearchResult `json:"movie_results"`
+	TvResults        []tmdbSearchResult `json:"tv_results"`
+	TvEpisodeResults []tmdbMedia        `json:"tv_episode_results"`
+}
+
+// tmdbProvider resolves media identity and fetches vote average through The Movie Database API
+type tmdbProvider struct{}
+
+// Name returns TMDb provider name
+func (p *tmdbProvider) Name() string { return providerTmdb }
+
+// Header returns TMDb table column header
+func (p *tmdbProvider) Header() string { return "TMDb rating" }
+
+// Scale returns maximum TMDb rating
+func (p *tmdbProvider) Scale() float64 { return 10 }
+
+// Configured returns true if TMDb API key is set
+func (p *tmdbProvider) Configured() bool { return tmdbKey != "" }
+
+// Resolve finds Movie or TV series on TMDb by IMDB Id if known or searches TMDb by title otherwise, and gets Movie or
+// TV series episode details
+func (p *tmdbProvider) Resolve(m *mediaInfo) error {
+	// TMDb episode details need season number, which daily shows and absolute episode numbers lack
+	if tmdbKey == "" || (m.isTv && m.season == 0) {
+		return errNoResolver
+	}
+
+	res, err := tmdbFindID(m.imdbID, m.isTv)
+	byImdbID := res.ID != 0
+	if res.ID == 0 {
+		if err != nil {
+			log.Debugf("Media with IMDB Id %v not found on TMDb: %v", m.imdbID, err)
+		}
+		res, err = tmdbSearch(m.title, m.year, m.isTv)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tmdbDetails(m, res.ID); err != nil {
+		return err
+	}
+
+	m.byImdbID = byImdbID
+	if m.isTv {
+		m.seriesTitle = res.Name
+	}
+	return nil
+}
+
+// Rating returns TMDb vote average obtained during resolution, or looks up media on TMDb by its IMDB Id if media has
+// been resolved by another provider
+func (p *tmdbProvider) Rating(m *mediaInfo) (string, error) {
+	if v, ok := m.known[providerTmdb]; ok {
+		return v, nil
+	}
+	if tmdbKey == "" || m.imdbID == "" {
+		return "N/A", nil
+	}
+
+	// Find TMDb Id from IMDB Id (of a Movie or TV series) and fetch details into a copy of media information, so
+	// that identity resolved by another provider stays intact
+	var res tmdbFindResponse
+	err := tmdbGet("/find/"+m.imdbID, url.Values{"external_source": {"imdb_id"}}, &res)
+	if err != nil {
+		return "", err
+	}
+
+	// IMDB Id of a TV series episode directly results in episode details
+	if len(res.TvEpisodeResults) > 0 {
+		return formatVoteAverage(res.TvEpisodeResults[0]), nil
+	}
+
+	results := res.MovieResults
+	if m.isTv {
+		results = res.TvResults
+	}
+	if len(results) == 0 {
+		log.Debugf("Media with IMDB Id %v not found on TMDb", m.imdbID)
+		return "N/A", nil
+	}
+
+	tm := *m
+	tm.known = make(map[string]string)
+	if err := tmdbDetails(&tm, results[0].ID); err != nil {
+		return "", err
+	}
+
+	return tm.known[providerTmdb], nil
+}
+
+// tmdbFindID returns TMDb Movie or TV series by its IMDB Id, or result with zero TMDb Id if it is not found
+func tmdbFindID(imdbID string, isTv bool) (tmdbSearchResult, error) {
+	if imdbID == "" {
+		return tmdbSearchResult{}, nil
+	}
+
+	var res tmdbFindResponse
+	if err := tmdbGet("/find/"+imdbID, url.Values{"external_source": {"imdb_id"}}, &res); err != nil {
+		return tmdbSearchResult{}, err
+	}
+
+	results := res.MovieResults
+	if isTv {
+		results = res.TvResults
+	}
+	if len(results) == 0 {
+		return tmdbSearchResult{}, nil
+	}
+
+	return results[0], nil
+}
+
+// tmdbSearch searches TMDb for Movie or TV series and returns the first result within a year of a given media year
+func tmdbSearch(mediaTitle string, mediaYear int, isTv bool) (tmdbSearchResult, error) {
+	path := "/search/movie"
+	params := url.Values{"query": {mediaTitle}}
+	if isTv {
+		path = "/search/tv"
+	}
+
+	var res tmdbSearchResponse
+	if err := tmdbGet(path, params, &res); err != nil {
+		return tmdbSearchResult{}, err
+	}
+
+	for _, v := range res.Results {
+		// TV series could only be matched by first air date, while later seasons could have been aired much later,
+		// so only Movies are matched by year
+		if mediaYear == 0 || isTv {
+			return v, nil
+		}
+
+		// Actual release date and RIP date can have a 1-year offset
+		if absInt(dateYear(v.ReleaseDate)-mediaYear) < 2 {
+			return v, nil
+		}
+	}
+
+	return tmdbSearchResult{}, fmt.Errorf("media not found on TMDb")
+}
+
+// tmdbDetails fetches Movie or TV series episode details from TMDb and stores media identity and vote average
+func tmdbDetails(m *mediaInfo, id int) error {
+	var media tmdbMedia
+
+	if !m.isTv {
+		if err := tmdbGet("/movie/"+strconv.Itoa(id), nil, &media); err != nil {
+			return err
+		}
+
+		m.resolvedTitle = media.Title
+		m.resolvedYear = zString(dateYear(media.ReleaseDate))
+		if media.ImdbID != "" {
+			m.imdbID = media.ImdbID
+		}
+	} else {
+		path := fmt.Sprintf("/tv/%d/season/%d/episode/%d", id, m.season, m.episode)
+		if err := tmdbGet(path, nil, &media); err != nil {
+			return err
+		}
+
+		m.resolvedTitle = media.Name
+		m.resolvedYear = zString(dateYear(media.AirDate))
+
+		// TV series IMDB Id allows other providers to query TV series episodes
+		var ids tmdbMedia
+		if err := tmdbGet(fmt.Sprintf("/tv/%d/external_ids", id), nil, &ids); err == nil && ids.ImdbID != "" {
+			m.imdbID = ids.ImdbID
+		}
+	}
+
+	m.known[providerTmdb] = formatVoteAverage(media)
+	return nil
+}
+
+// formatVoteAverage returns TMDb vote average with a single decimal, or N/A if nobody has voted yet
+func formatVoteAverage(media tmdbMedia) string {
+	if media.VoteCount == 0 {
+		return "N/A"
+	}
+	return strconv.FormatFloat(media.VoteAverage, 'f', 1, 64)
+}
+
+// tmdbGet does a HTTP GET on TMDb API endpoint and decodes JSON response
+func tmdbGet(path string, params url.Values, v interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("api_key", tmdbKey)
+
+	return getJSON(tmdbBaseUrl+path+"?"+params.Encode(), v)
+}
@@ -0,0 +1,204 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tmdbTestResponses maps TMDb API paths to canned JSON responses
+var tmdbTestResponses = map[string]string{
+	"/search/movie": `{"results": [
+		{"id": 1, "title": "Heat", "release_date": "1986-07-11"},
+		{"id": 2, "title": "Heat", "release_date": "1995-12-15"}]}`,
+	"/search/tv": `{"results": [{"id": 10, "name": "Friends", "first_air_date": "1994-09-22"}]}`,
+	"/movie/2": `{"id": 2, "imdb_id": "tt0113277", "title": "Heat", "release_date": "1995-12-15",
+		"vote_average": 7.9, "vote_count": 7000}`,
+	"/movie/3": `{"id": 3, "title": "Obscure", "release_date": "2019-01-01", "vote_average": 0,
+		"vote_count": 0}`,
+	"/tv/10/season/1/episode/2": `{"id": 100, "name": "The One with the Sonogram at the End",
+		"air_date": "1994-09-29", "vote_average": 7.25, "vote_count": 30}`,
+	"/tv/10/external_ids": `{"id": 10, "imdb_id": "tt0108778"}`,
+	"/find/tt0113277": `{"movie_results": [{"id": 2, "title": "Heat"}], "tv_results": [],
+		"tv_episode_results": []}`,
+	"/find/tt0108778": `{"movie_results": [], "tv_results": [{"id": 10, "name": "Friends"}],
+		"tv_episode_results": []}`,
+	"/find/tt0583459": `{"movie_results": [], "tv_results": [], "tv_episode_results": [
+		{"id": 100, "name": "The One with the Sonogram at the End", "vote_average": 7.25, "vote_count": 30}]}`,
+	"/find/tt0000000": `{"movie_results": [], "tv_results": [], "tv_episode_results": []}`,
+}
+
+// newTmdbTestServer starts TMDb API stand-in and points TMDb provider to it
+func newTmdbTestServer() func() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api_key") != "test" {
+			http.Error(w, `{"status_message": "Invalid API key"}`, http.StatusUnauthorized)
+			return
+		}
+		v, ok := tmdbTestResponses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(v))
+	}))
+
+	oldUrl, oldKey := tmdbBaseUrl, tmdbKey
+	tmdbBaseUrl, tmdbKey = ts.URL, "test"
+	return func() {
+		tmdbBaseUrl, tmdbKey = oldUrl, oldKey
+		ts.Close()
+	}
+}
+
+func TestTmdbSearch(t *testing.T) {
+	defer newTmdbTestServer()()
+
+	tests := []struct {
+		title   string
+		year    int
+		isTv    bool
+		id      int
+		wantErr bool
+	}{
+		{"Heat", 1995, false, 2, false},
+		{"Heat", 1996, false, 2, false}, // RIP year is off by one
+		{"Heat", 1994, false, 2, false},
+		{"Heat", 1987, false, 1, false},
+		{"Heat", 0, false, 1, false},
+		{"Heat", 2005, false, 0, true},
+		{"Friends", 2003, true, 10, false}, // TV series are not matched by year
+	}
+
+	for _, tt := range tests {
+		res, err := tmdbSearch(tt.title, tt.year, tt.isTv)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("tmdbSearch(%q, %v, %v) error = %v, wantErr %v", tt.title, tt.year, tt.isTv, err, tt.wantErr)
+			continue
+		}
+		if res.ID != tt.id {
+			t.Errorf("tmdbSearch(%q, %v, %v) = %v, want %v", tt.title, tt.year, tt.isTv, res.ID, tt.id)
+		}
+	}
+}
+
+func TestTmdbResolve(t *testing.T) {
+	defer newTmdbTestServer()()
+
+	tests := []struct {
+		name                            string
+		m                               *mediaInfo
+		imdbID                          string
+		title, year, imdbIDWant, rating string
+	}{
+		{"movie by title", newMediaInfo("Heat", 1996, 0, 0), "", "Heat", "1995", "tt0113277", "7.9"},
+		{"movie by IMDB Id", newMediaInfo("Wrong Title", 0, 0, 0), "tt0113277", "Heat", "1995", "tt0113277", "7.9"},
+		{"episode by title", newMediaInfo("Friends", 0, 1, 2), "", "The One with the Sonogram at the End",
+			"1994", "tt0108778", "7.2"},
+		{"episode by series IMDB Id", newMediaInfo("Wrong Title", 0, 1, 2), "tt0108778",
+			"The One with the Sonogram at the End", "1994", "tt0108778", "7.2"},
+	}
+
+	p := &tmdbProvider{}
+	for _, tt := range tests {
+		tt.m.imdbID = tt.imdbID
+		if err := p.Resolve(tt.m); err != nil {
+			t.Errorf("%v: Resolve() error = %v", tt.name, err)
+			continue
+		}
+		if tt.m.resolvedTitle != tt.title || tt.m.resolvedYear != tt.year || tt.m.imdbID != tt.imdbIDWant {
+			t.Errorf("%v: Resolve() = %q/%q/%q, want %q/%q/%q", tt.name, tt.m.resolvedTitle, tt.m.resolvedYear,
+				tt.m.imdbID, tt.title, tt.year, tt.imdbIDWant)
+		}
+		if v := tt.m.known[providerTmdb]; v != tt.rating {
+			t.Errorf("%v: rating = %q, want %q", tt.name, v, tt.rating)
+		}
+	}
+}
+
+func TestTmdbRating(t *testing.T) {
+	defer newTmdbTestServer()()
+
+	tests := []struct {
+		name   string
+		imdbID string
+		isTv   bool
+		rating string
+	}{
+		{"movie", "tt0113277", false, "7.9"},
+		{"TV series episode by series IMDB Id", "tt0108778", true, "7.2"},
+		{"TV series episode by episode IMDB Id", "tt0583459", true, "7.2"},
+		{"unknown IMDB Id", "tt0000000", false, "N/A"},
+		{"no IMDB Id", "", false, "N/A"},
+	}
+
+	p := &tmdbProvider{}
+	for _, tt := range tests {
+		m := newMediaInfo("Title", 0, 0, 0)
+		if tt.isTv {
+			m = newMediaInfo("Title", 0, 1, 2)
+		}
+		m.imdbID = tt.imdbID
+
+		v, err := p.Rating(m)
+		if err != nil {
+			t.Errorf("%v: Rating() error = %v", tt.name, err)
+			continue
+		}
+		if v != tt.rating {
+			t.Errorf("%v: Rating() = %q, want %q", tt.name, v, tt.rating)
+		}
+	}
+}
+
+func TestTmdbNoVotes(t *testing.T) {
+	defer newTmdbTestServer()()
+
+	m := newMediaInfo("Obscure", 2019, 0, 0)
+	if err := tmdbDetails(m, 3); err != nil {
+		t.Fatalf("tmdbDetails() error = %v", err)
+	}
+	if v := m.known[providerTmdb]; v != "N/A" {
+		t.Errorf("rating without votes = %q, want N/A", v)
+	}
+}
+
+func TestTmdbHTTPError(t *testing.T) {
+	defer newTmdbTestServer()()
+
+	// Missing media results in HTTP 404
+	if err := tmdbDetails(newMediaInfo("Missing", 0, 0, 0), 404); err == nil {
+		t.Error("tmdbDetails() with HTTP 404 response succeeded, want error")
+	}
+
+	// Invalid API key results in HTTP 401
+	tmdbKey = "invalid"
+	if _, err := tmdbSearch("Heat", 1995, false); err == nil {
+		t.Error("tmdbSearch() with HTTP 401 response succeeded, want error")
+	}
+	if _, err := (&tmdbProvider{}).Rating(&mediaInfo{imdbID: "tt0113277", known: map[string]string{}}); err == nil {
+		t.Error("Rating() with HTTP 401 response succeeded, want error")
+	}
+}
@@ -0,0 +1,227 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const providerTvmaze = "tvmaze"
+
+var tvmazeBaseUrl = "https://api.tvmaze.com" // TVmaze API endpoint
+
+// tvmazeRating is TVmaze show or episode rating, which is null when not rated yet
+type tvmazeRating struct {
+	Average *float64 `json:"average"`
+}
+
+// tvmazeShow holds TVmaze TV series details
+type tvmazeShow struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Premiered string `json:"premiered"`
+	Externals struct {
+		Imdb string `json:"imdb"`
+	} `json:"externals"`
+}
+
+// tvmazeEpisode holds TVmaze TV series episode details
+type tvmazeEpisode struct {
+	ID      int          `json:"id"`
+	Name    string       `json:"name"`
+	Season  int          `json:"season"`
+	Number  int          `json:"number"`
+	AirDate string       `json:"airdate"`
+	Rating  tvmazeRating `json:"rating"`
+}
+
+// tvmazeProvider resolves TV series episodes and fetches episode ratings through TVmaze API
+type tvmazeProvider struct{}
+
+// Name returns TVmaze provider name
+func (p *tvmazeProvider) Name() string { return providerTvmaze }
+
+// Header returns TVmaze table column header
+func (p *tvmazeProvider) Header() string { return "TVmaze rating" }
+
+// Scale returns maximum TVmaze rating
+func (p *tvmazeProvider) Scale() float64 { return 10 }
+
+// TvOnly returns true as TVmaze rates TV series episodes only
+func (p *tvmazeProvider) TvOnly() bool { return true }
+
+// Resolve searches TVmaze for TV series and gets episode by season and episode number, by air date for daily shows or
+// by absolute episode number; Movies are not supported
+func (p *tvmazeProvider) Resolve(m *mediaInfo) error {
+	if !m.isTv {
+		return errNoResolver
+	}
+
+	show, err := tvmazeFindShow(m)
+	if err != nil {
+		return err
+	}
+
+	ep, err := tvmazeFindEpisode(show.ID, m)
+	if err != nil {
+		return err
+	}
+
+	m.seriesTitle = show.Name
+	m.byImdbID = m.imdbID != "" && m.imdbID == show.Externals.Imdb
+
+	// Daily shows and absolute episode numbers are resolved into regular season and episode numbers
+	m.season = ep.Season
+	m.episode = ep.Number
+	m.resolvedTitle = ep.Name
+	m.resolvedYear = zString(dateYear(ep.AirDate))
+	m.airDate = ep.AirDate
+	if m.imdbID == "" {
+		m.imdbID = show.Externals.Imdb
+	}
+	m.known[providerTvmaze] = formatTvmazeRating(ep.Rating)
+
+	return nil
+}
+
+// Rating returns TVmaze episode rating obtained during resolution, or looks up TV series on TVmaze (by IMDB Id if
+// known) and gets the episode rating
+func (p *tvmazeProvider) Rating(m *mediaInfo) (string, error) {
+	if v, ok := m.known[providerTvmaze]; ok {
+		return v, nil
+	}
+	if !m.isTv {
+		return "N/A", nil
+	}
+
+	show, err := tvmazeFindShow(m)
+	if err != nil {
+		return "", err
+	}
+
+	ep, err := tvmazeFindEpisode(show.ID, m)
+	if err != nil {
+		return "", err
+	}
+
+	return formatTvmazeRating(ep.Rating), nil
+}
+
+// tvmazeFindShow looks up TV series on TVmaze by IMDB Id if known, falling back to title search
+func tvmazeFindShow(m *mediaInfo) (tvmazeShow, error) {
+	var show tvmazeShow
+	if m.imdbID != "" {
+		err := getJSON(tvmazeBaseUrl+"/lookup/shows?"+url.Values{"imdb": {m.imdbID}}.Encode(), &show)
+		if err == nil {
+			return show, nil
+		}
+		// IMDB Id could also be an episode Id, so retry with TV series title
+		log.Debugf("TV series with IMDB Id %v not found on TVmaze: %v", m.imdbID, err)
+	}
+
+	return tvmazeSearch(m.title)
+}
+
+// tvmazeSearch returns the best TVmaze TV series match for a given title
+func tvmazeSearch(mediaTitle string) (tvmazeShow, error) {
+	var show tvmazeShow
+	err := getJSON(tvmazeBaseUrl+"/singlesearch/shows?"+url.Values{"q": {mediaTitle}}.Encode(), &show)
+	if err != nil {
+		return show, tvmazeError(err, "TV series %q not found on TVmaze", mediaTitle)
+	}
+
+	return show, nil
+}
+
+// tvmazeFindEpisode returns TVmaze TV series episode by air date, absolute episode number or season and episode number
+func tvmazeFindEpisode(showID int, m *mediaInfo) (tvmazeEpisode, error) {
+	switch {
+	case m.season == 0 && m.airDate != "":
+		return tvmazeEpisodeByDate(showID, m.airDate)
+	case m.absolute && m.season == 0:
+		return tvmazeEpisodeByAbsolute(showID, m.episode)
+	}
+	return tvmazeEpisodeByNumber(showID, m.season, m.episode)
+}
+
+// tvmazeEpisodeByDate returns TVmaze TV series episode aired on a given date; the first one is used when several
+// episodes were aired the same day
+func tvmazeEpisodeByDate(showID int, airDate string) (tvmazeEpisode, error) {
+	var eps []tvmazeEpisode
+	params := url.Values{"date": {airDate}}
+	err := getJSON(fmt.Sprintf("%v/shows/%d/episodesbydate?%v", tvmazeBaseUrl, showID, params.Encode()), &eps)
+	if err != nil || len(eps) == 0 {
+		return tvmazeEpisode{}, tvmazeError(err, "episode aired on %v not found on TVmaze", airDate)
+	}
+
+	return eps[0], nil
+}
+
+// tvmazeEpisodeByAbsolute returns TVmaze TV series episode by absolute episode number, counting regular episodes
+// across all seasons in airing order
+func tvmazeEpisodeByAbsolute(showID, episode int) (tvmazeEpisode, error) {
+	var eps []tvmazeEpisode
+	err := getJSON(fmt.Sprintf("%v/shows/%d/episodes", tvmazeBaseUrl, showID), &eps)
+	if err != nil || episode < 1 || episode > len(eps) {
+		return tvmazeEpisode{}, tvmazeError(err, "absolute episode %d not found on TVmaze", episode)
+	}
+
+	return eps[episode-1], nil
+}
+
+// tvmazeEpisodeByNumber returns TVmaze TV series episode by season and episode number
+func tvmazeEpisodeByNumber(showID, season, episode int) (tvmazeEpisode, error) {
+	var ep tvmazeEpisode
+	params := url.Values{"season": {strconv.Itoa(season)}, "number": {strconv.Itoa(episode)}}
+	err := getJSON(fmt.Sprintf("%v/shows/%d/episodebynumber?%v", tvmazeBaseUrl, showID, params.Encode()), &ep)
+	if err != nil {
+		return ep, tvmazeError(err, "episode S%02dE%02d not found on TVmaze", season, episode)
+	}
+
+	return ep, nil
+}
+
+// tvmazeError adds context to TVmaze lookup error; network errors are returned unchanged so that unmatched media is
+// reported as such
+func tvmazeError(err error, format string, a ...interface{}) error {
+	if _, ok := err.(net.Error); ok {
+		return err
+	}
+	if err != nil {
+		format += ": %v"
+		a = append(a, err)
+	}
+	return &matchError{reason: reasonNotFound, err: fmt.Errorf(format, a...)}
+}
+
+// formatTvmazeRating returns TVmaze rating with a single decimal, or N/A if not rated yet
+func formatTvmazeRating(r tvmazeRating) string {
+	if r.Average == nil {
+		return "N/A"
+	}
+	return strconv.FormatFloat(*r.Average, 'f', 1, 64)
+}